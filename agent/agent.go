@@ -3,18 +3,65 @@ package agent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+	"agent/logger"
+	"agent/redact"
 	"agent/tools"
+	"agent/tracing"
 )
 
 type Agent struct {
 	client         *anthropic.Client
 	getUserMessage func() (string, bool)
 	tools          []tools.ToolDefinition
+	conversation   []anthropic.MessageParam // history kept by Send
+
+	// AutoGoCheck runs go build and go vet after a tool changes Go files and
+	// appends any problems to that tool's result.
+	AutoGoCheck bool
+
+	// PostEditHooks run after every successful tool call that changed files;
+	// their output is appended to the tool's result.
+	PostEditHooks []PostEditHook
+
+	// MaxResultBytes is the size above which tool results are truncated,
+	// with the full result saved to a scratch file. Zero means
+	// DefaultMaxResultBytes and a negative value disables truncation.
+	MaxResultBytes int
+
+	// ToolResultLimits overrides MaxResultBytes for individual tools by name.
+	ToolResultLimits map[string]int
+
+	// Redactor, if set, masks secrets in tool results and errors before
+	// they are sent to the model.
+	Redactor *redact.Redactor
+
+	// Provider, if set, replaces the Anthropic API as the model.
+	Provider Provider
+
+	// ToolHook, if set, is called for every tool call with a function that
+	// runs the tool, and returns the result to use. Replays use it to return
+	// recorded results instead of running tools, or to compare them.
+	ToolHook func(call ToolCall, run func() (string, error)) (string, error)
+
+	scratch scratch
 }
 
+// PostEditHook inspects the files changed by a tool call and returns feedback
+// for the model, or an empty string if there is nothing to report.
+type PostEditHook func(ctx context.Context, changed []string) string
+
 func NewAgent(
 	client *anthropic.Client,
 	getUserMessage func() (string, bool),
@@ -27,6 +74,11 @@ func NewAgent(
 	}
 }
 
+// Tools returns the tools available to the agent.
+func (a *Agent) Tools() []tools.ToolDefinition {
+	return a.tools
+}
+
 // ClaudeResponse represents a single Claude response, which may include text and tool-use blocks.
 type ClaudeResponse struct {
 	Texts    []string
@@ -69,94 +121,311 @@ func (a *Agent) Run(ctx context.Context) error {
 
 	fmt.Println("Chat with Claude (use 'ctrl-c' to quit)")
 
-	readUserInput := true
+	var printMu sync.Mutex
+	printEvent := func(event Event) {
+		printMu.Lock()
+		defer printMu.Unlock()
+		switch event.Kind {
+		case EventText:
+			fmt.Printf("\u001b[93mClaude\u001b[0m: %s\n", event.Text)
+		case EventToolStart:
+			fmt.Printf("\u001b[92mtool\u001b[0m: %s(%s)\n", event.Call.Name, event.Call.Input)
+		}
+	}
+
 	for {
-		if readUserInput {
-			fmt.Print("\u001b[94mYou\u001b[0m: ")
-			userInput, ok := a.getUserMessage()
-			if !ok {
-				break
-			}
+		fmt.Print("\u001b[94mYou\u001b[0m: ")
+		userInput, ok := a.getUserMessage()
+		if !ok {
+			break
+		}
+
+		logger.UserMessage(userInput)
+		userMessage := anthropic.NewUserMessage(anthropic.NewTextBlock(userInput))
+		conversation = append(conversation, userMessage)
 
-			userMessage := anthropic.NewUserMessage(anthropic.NewTextBlock(userInput))
-			conversation = append(conversation, userMessage)
+		var err error
+		conversation, _, err = a.converse(ctx, conversation, 0, printEvent)
+		if err != nil {
+			return err
 		}
+	}
 
+	return nil
+}
+
+// converse sends conversation to the model and executes the tools it
+// requests, repeating until the model replies without tool calls. It returns
+// the extended conversation and the text of the final reply. maxTurns bounds
+// the number of model requests; zero means no limit.
+func (a *Agent) converse(ctx context.Context, conversation []anthropic.MessageParam, maxTurns int, onEvent func(Event)) (_ []anthropic.MessageParam, _ string, err error) {
+	ctx, span := tracing.Start(ctx, "agent.turn", "messages", len(conversation))
+	defer func() {
+		span.RecordError(err)
+		span.End()
+	}()
+
+	for turn := 0; maxTurns == 0 || turn < maxTurns; turn++ {
+		span.SetAttributes("model_requests", turn+1)
 		message, err := a.runInference(ctx, conversation)
 		if err != nil {
-			return err
+			return conversation, "", err
 		}
 		conversation = append(conversation, message.ToParam())
 
-		toolResults := []anthropic.ContentBlockParamUnion{}
+		var texts []string
+		var calls []ToolCall
 		for _, content := range message.Content {
 			switch content.Type {
 			case "text":
-				fmt.Printf("\u001b[93mClaude\u001b[0m: %s\n", content.Text)
+				texts = append(texts, content.Text)
+				if onEvent != nil {
+					onEvent(Event{Kind: EventText, Text: content.Text})
+				}
 			case "tool_use":
-				result := a.executeTool(content.ID, content.Name, content.Input)
-				toolResults = append(toolResults, result)
+				calls = append(calls, ToolCall{ID: content.ID, Name: content.Name, Input: content.Input})
 			}
 		}
-		if len(toolResults) == 0 {
-			readUserInput = true
-			continue
+		if len(calls) == 0 {
+			return conversation, strings.Join(texts, "\n"), nil
 		}
-		readUserInput = false
+		toolResults := a.executeToolCalls(ctx, calls, onEvent)
 		conversation = append(conversation, anthropic.NewUserMessage(toolResults...))
 	}
+	return conversation, "", fmt.Errorf("task did not finish within %d turns", maxTurns)
+}
 
+// Send adds userInput to the agent's ongoing conversation and works on it
+// until the model replies without tool calls, reporting progress to onEvent.
+// Calls must not overlap.
+func (a *Agent) Send(ctx context.Context, userInput string, onEvent func(Event)) error {
+	logger.UserMessage(userInput)
+	conversation := append(a.conversation, anthropic.NewUserMessage(anthropic.NewTextBlock(userInput)))
+	conversation, _, err := a.converse(ctx, conversation, 0, onEvent)
+	if err != nil {
+		// The failed exchange is dropped, since the API rejects histories
+		// with a tool_use block that is not followed by its result.
+		return err
+	}
+	a.conversation = conversation
 	return nil
 }
 
+// maxTaskTurns bounds the number of model requests RunTask makes.
+const maxTaskTurns = 50
+
+// RunTask runs a non-interactive session for prompt: it executes every tool
+// the model requests until the model answers without tool calls, and
+// returns that final answer.
+func (a *Agent) RunTask(ctx context.Context, prompt string) (string, error) {
+	logger.UserMessage(prompt)
+	conversation := []anthropic.MessageParam{
+		anthropic.NewUserMessage(anthropic.NewTextBlock(prompt)),
+	}
+	_, answer, err := a.converse(ctx, conversation, maxTaskTurns, nil)
+	return answer, err
+}
+
 // ExecuteTool is a public wrapper for tool execution, allowing external packages to call tools and get (string, error).
 func (a *Agent) ExecuteTool(name string, input json.RawMessage) (string, error) {
-	for _, toolDef := range a.tools {
-		if toolDef.Name == name {
-			return toolDef.Function(input)
-		}
-	}
-	return "", fmt.Errorf("tool not found: %s", name)
+	return a.runTool(context.Background(), "", name, input)
 }
 
-func (a *Agent) executeTool(id, name string, input json.RawMessage) anthropic.ContentBlockParamUnion {
-	var toolDef tools.ToolDefinition
-	var found bool
+func (a *Agent) findTool(name string) (tools.ToolDefinition, bool) {
 	for _, tool := range a.tools {
 		if tool.Name == name {
-			toolDef = tool
-			found = true
-			break
+			return tool, true
 		}
 	}
-	if !found {
-		return anthropic.NewToolResultBlock(id, "tool not found", true)
+	return tools.ToolDefinition{}, false
+}
+
+// runTool executes a tool, applying the checks shared by the CLI loop and
+// the TUI and the result size limits, and logs the call. id is the tool_use
+// ID, if the call was requested by the model.
+func (a *Agent) runTool(ctx context.Context, id, name string, input json.RawMessage) (string, error) {
+	ctx, span := tracing.Start(ctx, "tool.call", "tool", name, "tool_use_id", id, "input_bytes", len(input))
+	defer span.End()
+	logger.ToolCall(id, name, input)
+	started := time.Now()
+	run := func() (string, error) {
+		return a.runToolLimited(ctx, name, input)
+	}
+	var response string
+	var err error
+	if a.ToolHook != nil {
+		response, err = a.ToolHook(ToolCall{ID: id, Name: name, Input: input}, run)
+	} else {
+		response, err = run()
+	}
+	if err != nil {
+		span.RecordError(err)
+		logger.ToolResult(id, name, "", err, time.Since(started))
+		return "", err
 	}
-	fmt.Printf("\u001b[92mtool\u001b[0m: %s(%s)\n", name, input)
-	response, err := toolDef.Function(input)
+	span.SetAttributes("result_bytes", len(response))
+	logger.ToolResult(id, name, response, nil, time.Since(started))
+	return response, nil
+}
+
+// runToolLimited runs a tool and masks secrets in its result, truncating it
+// if it exceeds the tool's size limit.
+func (a *Agent) runToolLimited(ctx context.Context, name string, input json.RawMessage) (string, error) {
+	response, err := a.runToolChecked(ctx, name, input)
 	if err != nil {
-		return anthropic.NewToolResultBlock(id, err.Error(), true)
+		if limited := a.limitResult(name, a.Redactor.Redact(err.Error())); limited != err.Error() {
+			err = errors.New(limited)
+		}
+		return "", err
+	}
+	return a.limitResult(name, a.Redactor.Redact(response)), nil
+}
+
+// runToolChecked validates a tool's input, executes it and runs the
+// post-edit checks.
+func (a *Agent) runToolChecked(ctx context.Context, name string, input json.RawMessage) (string, error) {
+	toolDef, found := a.findTool(name)
+	if !found {
+		return "", fmt.Errorf("tool not found: %s", name)
+	}
+	if len(strings.TrimSpace(string(input))) == 0 {
+		input = json.RawMessage("{}")
+	}
+	if err := tools.ValidateInput(toolDef.InputSchema, input); err != nil {
+		return "", err
+	}
+	_, span := tracing.Start(ctx, "tool.execute", "tool", name)
+	response, err := callTool(toolDef, input)
+	span.RecordError(err)
+	span.End()
+
+	changed := tools.TakeChangedFiles()
+	if err != nil || len(changed) == 0 {
+		return response, err
+	}
+	ctx, span = tracing.Start(ctx, "tool.post_edit", "changed_files", len(changed))
+	defer span.End()
+	if a.AutoGoCheck {
+		response += runPostEdit("go check", func() string { return a.checkGoFiles(ctx, changed) })
+	}
+	for _, hook := range a.PostEditHooks {
+		response += runPostEdit("post-edit hook", func() string { return hook(ctx, changed) })
+	}
+	return response, nil
+}
+
+// runPostEdit runs a post-edit check, converting a panic into a note like
+// callTool does, since the edit itself has already been made.
+func runPostEdit(name string, check func() string) (note string) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("post-edit check panicked", fmt.Errorf("%v", r), "check", name, "stack", string(debug.Stack()))
+			note = fmt.Sprintf("\nWarning: the %s failed with an internal error: %v", name, r)
+		}
+	}()
+	return check()
+}
+
+// callTool runs a tool's function, converting a panic into an error so that
+// a faulty tool cannot take the whole program down. The stack is logged.
+func callTool(toolDef tools.ToolDefinition, input json.RawMessage) (response string, err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			logger.Error("tool panicked", fmt.Errorf("%v", r), "tool", toolDef.Name, "input", string(input), "stack", string(debug.Stack()))
+			response, err = "", fmt.Errorf("tool %s failed with an internal error: %v", toolDef.Name, r)
+		}
+	}()
+	return toolDef.Function(input)
+}
+
+// checkGoFiles builds and vets the workspace module if any of the changed
+// files is a Go file, returning a note describing the problems found, if any.
+func (a *Agent) checkGoFiles(ctx context.Context, changed []string) string {
+	touchedGo := false
+	for _, path := range changed {
+		if strings.HasSuffix(path, ".go") {
+			touchedGo = true
+			break
+		}
 	}
-	return anthropic.NewToolResultBlock(id, response, false)
+	if !touchedGo {
+		return ""
+	}
+	if _, err := os.Stat(filepath.Join(tools.WorkspaceRoot(), "go.mod")); err != nil {
+		return ""
+	}
+
+	result, err := tools.RunGoCheck(ctx, tools.GoCheckInput{SkipTests: true})
+	if err != nil || result.Passed {
+		return ""
+	}
+	return "\n\nAutomatic go build and go vet after this change reported problems:\n" + result.String()
 }
 
 func (a *Agent) runInference(ctx context.Context, conversation []anthropic.MessageParam) (*anthropic.Message, error) {
 	anthropicTools := []anthropic.ToolUnionParam{}
 	for _, tool := range a.tools {
-		anthropicTools = append(anthropicTools, anthropic.ToolUnionParam{
-			OfTool: &anthropic.ToolParam{
-				Name:        tool.Name,
-				Description: anthropic.String(tool.Description),
-				InputSchema: tool.InputSchema,
-			},
-		})
-	}
-	message, err := a.client.Messages.New(ctx, anthropic.MessageNewParams{
-		Model:     anthropic.ModelClaude3_7SonnetLatest,
+		anthropicTools = append(anthropicTools, anthropic.ToolUnionParam{OfTool: toolParam(tool)})
+	}
+	model := anthropic.ModelClaude3_7SonnetLatest
+	ctx, span := tracing.Start(ctx, "model.request", "model", string(model), "messages", len(conversation), "tools", len(anthropicTools))
+	defer span.End()
+	logger.ModelRequest(string(model), len(conversation), len(anthropicTools), MessagesDigest(conversation))
+	message, err := a.provider().NewMessage(ctx, anthropic.MessageNewParams{
+		Model:     model,
 		MaxTokens: int64(1024),
 		Messages:  conversation,
 		Tools:     anthropicTools,
 	})
+	if err != nil {
+		span.RecordError(err)
+		logger.Error("model request failed", err)
+		return message, err
+	}
+	span.SetAttributes("stop_reason", string(message.StopReason),
+		"input_tokens", message.Usage.InputTokens, "output_tokens", message.Usage.OutputTokens)
 
-	return message, err
+	var texts, toolCalls []string
+	for _, content := range message.Content {
+		switch content.Type {
+		case "text":
+			texts = append(texts, content.Text)
+		case "tool_use":
+			toolCalls = append(toolCalls, content.Name)
+		}
+	}
+	logger.ModelResponse(strings.Join(texts, "\n"), toolCalls, string(message.StopReason), message.Usage.InputTokens, message.Usage.OutputTokens, json.RawMessage(message.RawJSON()))
+	return message, nil
+}
+
+// toolParam describes a tool to the API. The SDK adds a "-" property to
+// every input schema it marshals, so the schema is sent as rendered by
+// tools.SchemaJSON instead.
+func toolParam(tool tools.ToolDefinition) *anthropic.ToolParam {
+	param := &anthropic.ToolParam{
+		Name:        tool.Name,
+		Description: anthropic.String(tool.Description),
+		InputSchema: tool.InputSchema,
+	}
+	if schema, err := tools.SchemaJSON(tool.InputSchema); err == nil {
+		param.WithExtraFields(map[string]any{"input_schema": schema})
+	}
+	return param
+}
+
+// traceAttempt records a span for each HTTP attempt of a model request, so
+// that retries show up in traces.
+func traceAttempt(req *http.Request, next option.MiddlewareNext) (*http.Response, error) {
+	_, span := tracing.Start(req.Context(), "model.attempt", "retry", req.Header.Get("X-Stainless-Retry-Count"))
+	defer span.End()
+	resp, err := next(req)
+	if err != nil {
+		span.RecordError(err)
+		return resp, err
+	}
+	span.SetAttributes("status_code", resp.StatusCode)
+	if resp.StatusCode >= 400 {
+		span.RecordError(errors.New(resp.Status))
+	}
+	return resp, nil
 }
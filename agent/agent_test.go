@@ -0,0 +1,77 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"agent/tools"
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// capturingProvider records the requests sent and replies with text.
+type capturingProvider struct {
+	requests []anthropic.MessageNewParams
+}
+
+func (p *capturingProvider) NewMessage(ctx context.Context, params anthropic.MessageNewParams) (*anthropic.Message, error) {
+	p.requests = append(p.requests, params)
+	var message anthropic.Message
+	err := json.Unmarshal([]byte(`{"role": "assistant", "content": [{"type": "text", "text": "Done."}]}`), &message)
+	return &message, err
+}
+
+// TestToolSchemasSent checks that the input schemas in requests are the
+// tools' schemas, without anything the SDK adds when marshalling them.
+func TestToolSchemasSent(t *testing.T) {
+	defs := tools.BuiltinDefinitions()
+	provider := &capturingProvider{}
+	a := NewAgent(nil, nil, defs)
+	a.Provider = provider
+	if _, err := a.RunTask(context.Background(), "Hello."); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := json.Marshal(provider.requests[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	var request struct {
+		Tools []struct {
+			Name        string          `json:"name"`
+			InputSchema json.RawMessage `json:"input_schema"`
+		} `json:"tools"`
+	}
+	if err := json.Unmarshal(data, &request); err != nil {
+		t.Fatal(err)
+	}
+	if len(request.Tools) != len(defs) {
+		t.Fatalf("sent %d tools, want %d", len(request.Tools), len(defs))
+	}
+	for i, tool := range request.Tools {
+		var schema map[string]any
+		if err := json.Unmarshal(tool.InputSchema, &schema); err != nil {
+			t.Fatalf("%s: %v", tool.Name, err)
+		}
+		if _, ok := schema["-"]; ok {
+			t.Errorf("%s: input_schema has a \"-\" property: %s", tool.Name, tool.InputSchema)
+		}
+		if schema["type"] != "object" || schema["properties"] == nil {
+			t.Errorf("%s: input_schema = %s", tool.Name, tool.InputSchema)
+		}
+		if tool.Name != defs[i].Name {
+			t.Errorf("tool %d is %s, want %s", i, tool.Name, defs[i].Name)
+		}
+	}
+}
+
+func TestRunPostEditRecovers(t *testing.T) {
+	note := runPostEdit("post-edit hook", func() string { panic("boom") })
+	if !strings.Contains(note, "post-edit hook failed with an internal error: boom") {
+		t.Errorf("note = %q, want the panic reported", note)
+	}
+	if note := runPostEdit("go check", func() string { return "\nok" }); note != "\nok" {
+		t.Errorf("note = %q, want the check's note", note)
+	}
+}
@@ -0,0 +1,37 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"agent/tools"
+)
+
+type DelegateTaskInput struct {
+	Task string `json:"task" jsonschema_description:"A complete, self-contained description of the task, including any files, constraints and the expected outcome."`
+}
+
+// DelegateTaskDefinition returns a tool that runs a whole agent session for a
+// task, with all of a's tools, and returns the agent's final answer.
+func (a *Agent) DelegateTaskDefinition() tools.ToolDefinition {
+	return tools.ToolDefinition{
+		Name: "delegate_task",
+		Description: `Hand a task to a coding agent working in this workspace and wait for its final answer.
+The agent can read, search and edit files, and run the other tools listed here, over as many steps as it needs.
+It does not see this conversation, so describe the task completely.
+`,
+		InputSchema: tools.GenerateSchema[DelegateTaskInput](),
+		Function: func(input json.RawMessage) (string, error) {
+			delegateTaskInput := DelegateTaskInput{}
+			err := json.Unmarshal(input, &delegateTaskInput)
+			if err != nil {
+				return "", err
+			}
+			if delegateTaskInput.Task == "" {
+				return "", fmt.Errorf("invalid input parameters")
+			}
+			return a.RunTask(context.Background(), delegateTaskInput.Task)
+		},
+	}
+}
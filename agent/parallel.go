@@ -0,0 +1,97 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"sync"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// maxParallelTools bounds how many read-only tools run at the same time.
+const maxParallelTools = 8
+
+// ToolCall is a tool_use block requested by the model.
+type ToolCall struct {
+	ID    string
+	Name  string
+	Input json.RawMessage
+}
+
+// EventKind distinguishes the events reported while the agent works.
+type EventKind int
+
+const (
+	// EventText carries text written by the model.
+	EventText EventKind = iota
+	// EventToolStart is sent when a tool call begins.
+	EventToolStart
+	// EventToolDone is sent when a tool call finishes, with its result or error.
+	EventToolDone
+)
+
+// Event reports progress of a conversation turn. Events for tool calls that
+// run concurrently may arrive from several goroutines at once.
+type Event struct {
+	Kind     EventKind
+	Text     string
+	Call     ToolCall
+	Result   string
+	Err      error
+	Duration time.Duration
+}
+
+// isReadOnly reports whether the named tool is known not to modify anything.
+func (a *Agent) isReadOnly(name string) bool {
+	tool, found := a.findTool(name)
+	return found && tool.ReadOnly
+}
+
+// executeToolCalls runs the tool calls of one model response and returns
+// their results in the order of calls. Consecutive read-only calls run
+// concurrently, at most maxParallelTools at a time; every other call runs on
+// its own, so a mutating tool never overlaps with anything, and its effects
+// are seen by the calls after it.
+func (a *Agent) executeToolCalls(ctx context.Context, calls []ToolCall, onEvent func(Event)) []anthropic.ContentBlockParamUnion {
+	results := make([]anthropic.ContentBlockParamUnion, len(calls))
+	for start := 0; start < len(calls); {
+		end := start + 1
+		if a.isReadOnly(calls[start].Name) {
+			for end < len(calls) && a.isReadOnly(calls[end].Name) {
+				end++
+			}
+		}
+
+		var wg sync.WaitGroup
+		slots := make(chan struct{}, maxParallelTools)
+		for i := start; i < end; i++ {
+			wg.Add(1)
+			slots <- struct{}{}
+			go func(i int) {
+				defer wg.Done()
+				defer func() { <-slots }()
+				results[i] = a.executeToolCall(ctx, calls[i], onEvent)
+			}(i)
+		}
+		wg.Wait()
+		start = end
+	}
+	return results
+}
+
+// executeToolCall runs one tool call, reporting its start and end.
+func (a *Agent) executeToolCall(ctx context.Context, call ToolCall, onEvent func(Event)) anthropic.ContentBlockParamUnion {
+	if onEvent != nil {
+		onEvent(Event{Kind: EventToolStart, Call: call})
+	}
+	started := time.Now()
+	response, err := a.runTool(ctx, call.ID, call.Name, call.Input)
+	if onEvent != nil {
+		onEvent(Event{Kind: EventToolDone, Call: call, Result: response, Err: err, Duration: time.Since(started)})
+	}
+	if err != nil {
+		return anthropic.NewToolResultBlock(call.ID, err.Error(), true)
+	}
+	return anthropic.NewToolResultBlock(call.ID, response, false)
+}
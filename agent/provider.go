@@ -0,0 +1,50 @@
+package agent
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/anthropics/anthropic-sdk-go/option"
+)
+
+// Provider sends a request to a model and returns its reply.
+type Provider interface {
+	NewMessage(ctx context.Context, params anthropic.MessageNewParams) (*anthropic.Message, error)
+}
+
+// anthropicProvider sends requests to the Anthropic API.
+type anthropicProvider struct {
+	client *anthropic.Client
+}
+
+func (p anthropicProvider) NewMessage(ctx context.Context, params anthropic.MessageNewParams) (*anthropic.Message, error) {
+	return p.client.Messages.New(ctx, params, option.WithMiddleware(traceAttempt))
+}
+
+// AnthropicProvider returns a Provider that sends requests to the Anthropic
+// API with client.
+func AnthropicProvider(client *anthropic.Client) Provider {
+	return anthropicProvider{client: client}
+}
+
+// provider returns the Provider requests are sent to.
+func (a *Agent) provider() Provider {
+	if a.Provider != nil {
+		return a.Provider
+	}
+	return anthropicProvider{client: a.client}
+}
+
+// MessagesDigest returns a hash of a conversation, recorded with each model
+// request so that a replay can tell whether it sends the same requests.
+func MessagesDigest(messages []anthropic.MessageParam) string {
+	data, err := json.Marshal(messages)
+	if err != nil {
+		return ""
+	}
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,121 @@
+package agent
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf8"
+)
+
+// DefaultMaxResultBytes is the tool result size above which results are
+// truncated, unless configured otherwise.
+const DefaultMaxResultBytes = 64 * 1024
+
+// scratch holds the files that full tool results are spilled to.
+type scratch struct {
+	mu    sync.Mutex
+	dir   string
+	count int
+}
+
+// save writes content to a new file in the scratch directory, creating the
+// directory on first use, and returns the file's path.
+func (s *scratch) save(toolName, content string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dir == "" {
+		dir, err := os.MkdirTemp("", "code-editing-agent-")
+		if err != nil {
+			return "", err
+		}
+		s.dir = dir
+	}
+	s.count++
+	path := filepath.Join(s.dir, fmt.Sprintf("%03d-%s.txt", s.count, toolName))
+	if err := os.WriteFile(path, []byte(content), 0o600); err != nil {
+		return "", err
+	}
+	return path, nil
+}
+
+// remove deletes the scratch directory and everything in it.
+func (s *scratch) remove() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.dir == "" {
+		return nil
+	}
+	err := os.RemoveAll(s.dir)
+	s.dir = ""
+	return err
+}
+
+// resultLimit returns the maximum result size for a tool; zero or less means
+// unlimited.
+func (a *Agent) resultLimit(name string) int {
+	if limit, ok := a.ToolResultLimits[name]; ok {
+		return limit
+	}
+	if a.MaxResultBytes != 0 {
+		return a.MaxResultBytes
+	}
+	return DefaultMaxResultBytes
+}
+
+// limitResult truncates a tool result that exceeds the tool's limit. The
+// full result is saved to a scratch file, whose path is given in a header
+// so the model can page through it with read_file.
+func (a *Agent) limitResult(name, result string) string {
+	limit := a.resultLimit(name)
+	if limit <= 0 || len(result) <= limit {
+		return result
+	}
+
+	lines := strings.Count(result, "\n")
+	if !strings.HasSuffix(result, "\n") {
+		lines++
+	}
+	var where string
+	if path, err := a.scratch.save(name, result); err != nil {
+		where = fmt.Sprintf("The full output could not be saved: %v.", err)
+	} else {
+		where = fmt.Sprintf("The full output is saved in %s; read it with read_file using offset and limit.", path)
+	}
+
+	// Keep the start and the end, where summaries and errors usually are,
+	// cutting at line boundaries, or at least between runes.
+	headEnd, tailStart := limit*3/4, len(result)-limit/4
+	for headEnd > 0 && !utf8.RuneStart(result[headEnd]) {
+		headEnd--
+	}
+	for tailStart < len(result) && !utf8.RuneStart(result[tailStart]) {
+		tailStart++
+	}
+	head := cutAtLine(result[:headEnd], false)
+	tail := cutAtLine(result[tailStart:], true)
+	omitted := strings.Count(result, "\n") - strings.Count(head, "\n") - strings.Count(tail, "\n")
+	return fmt.Sprintf("[Output of %s truncated: %d bytes in %d lines, over the limit of %d bytes. %s]\n%s\n[... %d lines omitted ...]\n%s",
+		name, len(result), lines, limit, where, head, omitted, tail)
+}
+
+// cutAtLine drops the partial line at the end of s, or at its start if
+// fromStart is set, unless s has no line break at all.
+func cutAtLine(s string, fromStart bool) string {
+	if fromStart {
+		if i := strings.IndexByte(s, '\n'); i >= 0 {
+			return s[i+1:]
+		}
+		return s
+	}
+	if i := strings.LastIndexByte(s, '\n'); i >= 0 {
+		return s[:i]
+	}
+	return s
+}
+
+// Close removes the scratch files holding full tool results.
+func (a *Agent) Close() error {
+	return a.scratch.remove()
+}
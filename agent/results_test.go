@@ -0,0 +1,22 @@
+package agent
+
+import (
+	"strings"
+	"testing"
+	"unicode/utf8"
+)
+
+func TestLimitResultKeepsRunes(t *testing.T) {
+	a := &Agent{MaxResultBytes: 100}
+	defer a.Close()
+	// A single line of three-byte runes, so the result can only be cut
+	// within the line.
+	result := strings.Repeat("世", 100)
+	limited := a.limitResult("read_file", result)
+	if !utf8.ValidString(limited) {
+		t.Errorf("truncated result is not valid UTF-8: %q", limited)
+	}
+	if !strings.Contains(limited, "truncated") {
+		t.Errorf("result was not truncated: %q", limited)
+	}
+}
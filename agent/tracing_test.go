@@ -0,0 +1,125 @@
+package agent
+
+import (
+	"context"
+	"encoding/json"
+	"testing"
+
+	"agent/tools"
+	"agent/tracing"
+	"agent/tracing/tracetest"
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// scriptedProvider returns the given responses, as Messages API JSON, in
+// order.
+type scriptedProvider []string
+
+func (p *scriptedProvider) NewMessage(ctx context.Context, params anthropic.MessageNewParams) (*anthropic.Message, error) {
+	var message anthropic.Message
+	err := json.Unmarshal([]byte((*p)[0]), &message)
+	*p = (*p)[1:]
+	return &message, err
+}
+
+type greetInput struct {
+	Name string `json:"name"`
+}
+
+// attr returns the value of the attribute key of span, or nil.
+func attr(span tracing.SpanData, key string) any {
+	for _, a := range span.Attrs {
+		if a.Key == key {
+			return a.Value
+		}
+	}
+	return nil
+}
+
+func TestTracing(t *testing.T) {
+	exporter := tracetest.Install(t)
+
+	greet := tools.ToolDefinition{
+		Name:        "greet",
+		Description: "Greet someone.",
+		InputSchema: tools.GenerateSchema[greetInput](),
+		Function: func(input json.RawMessage) (string, error) {
+			var in greetInput
+			err := json.Unmarshal(input, &in)
+			return "Hello, " + in.Name, err
+		},
+	}
+	a := NewAgent(nil, nil, []tools.ToolDefinition{greet})
+	a.Provider = &scriptedProvider{
+		`{"role": "assistant", "stop_reason": "tool_use", "usage": {"input_tokens": 10, "output_tokens": 5},
+		  "content": [{"type": "tool_use", "id": "toolu_1", "name": "greet", "input": {"name": "Ada"}}]}`,
+		`{"role": "assistant", "stop_reason": "end_turn", "usage": {"input_tokens": 20, "output_tokens": 3},
+		  "content": [{"type": "text", "text": "Done."}]}`,
+	}
+	if _, err := a.RunTask(context.Background(), "Greet Ada."); err != nil {
+		t.Fatal(err)
+	}
+
+	// Spans are exported as they end.
+	spans := exporter.Spans()
+	var names []string
+	for _, span := range spans {
+		names = append(names, span.Name)
+	}
+	want := []string{"model.request", "tool.execute", "tool.call", "model.request", "agent.turn"}
+	if len(names) != len(want) {
+		t.Fatalf("spans = %v, want %v", names, want)
+	}
+	for i := range want {
+		if names[i] != want[i] {
+			t.Fatalf("spans = %v, want %v", names, want)
+		}
+	}
+
+	turn := spans[4]
+	if turn.ParentID != "" {
+		t.Errorf("agent.turn has parent %s, want a root span", turn.ParentID)
+	}
+	for _, span := range spans {
+		if span.TraceID != turn.TraceID {
+			t.Errorf("%s is in trace %s, want %s", span.Name, span.TraceID, turn.TraceID)
+		}
+		if span.Err != "" {
+			t.Errorf("%s failed: %s", span.Name, span.Err)
+		}
+	}
+	for _, i := range []int{0, 2, 3} {
+		if spans[i].ParentID != turn.SpanID {
+			t.Errorf("%s is a child of %s, want agent.turn", spans[i].Name, spans[i].ParentID)
+		}
+	}
+	if spans[1].ParentID != spans[2].SpanID {
+		t.Errorf("tool.execute is a child of %s, want tool.call", spans[1].ParentID)
+	}
+
+	check := func(span tracing.SpanData, key string, want any) {
+		t.Helper()
+		if got := attr(span, key); got != want {
+			t.Errorf("%s %s = %v (%T), want %v (%T)", span.Name, key, got, got, want, want)
+		}
+	}
+	check(turn, "messages", 1)
+	check(turn, "model_requests", 2)
+
+	first, second := spans[0], spans[3]
+	check(first, "model", string(anthropic.ModelClaude3_7SonnetLatest))
+	check(first, "messages", 1)
+	check(first, "tools", 1)
+	check(first, "stop_reason", "tool_use")
+	check(first, "input_tokens", int64(10))
+	check(first, "output_tokens", int64(5))
+	check(second, "messages", 3)
+	check(second, "stop_reason", "end_turn")
+
+	call := spans[2]
+	check(call, "tool", "greet")
+	check(call, "tool_use_id", "toolu_1")
+	check(call, "input_bytes", len(`{"name": "Ada"}`))
+	check(call, "result_bytes", len("Hello, Ada"))
+	check(spans[1], "tool", "greet")
+}
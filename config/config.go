@@ -0,0 +1,472 @@
+package config
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// AppName is used for the per-user configuration and state directories.
+const AppName = "code-editing-agent"
+
+// WorkspaceDir is the per-project directory holding workspace configuration.
+const WorkspaceDir = ".agent"
+
+// Config holds the user and workspace settings.
+type Config struct {
+	Permissions Permissions `json:"permissions"`
+
+	// DisableAutoGoCheck turns off the build and vet run after tools edit Go files.
+	DisableAutoGoCheck bool `json:"disable_auto_go_check"`
+
+	// DisableGoFormat turns off goimports formatting of Go files written by tools.
+	DisableGoFormat bool `json:"disable_go_format"`
+
+	// Formatters are external commands run on files written by tools. Like
+	// LanguageServers and MCPServers, which also name commands to run, they
+	// are only taken from the workspace configuration of trusted workspaces.
+	Formatters []Formatter `json:"formatters"`
+
+	LanguageServers []LanguageServer `json:"language_servers"`
+
+	MCPServers []MCPServer `json:"mcp_servers"`
+
+	// TrustedWorkspaces are the absolute paths of the workspaces whose
+	// configuration may make the agent run commands. It is only read from
+	// the user configuration.
+	TrustedWorkspaces []string `json:"trusted_workspaces,omitempty"`
+
+	// Ignored explains the workspace settings that Load left out.
+	Ignored []string `json:"-"`
+
+	workspaceTrusted bool
+
+	ToolResults ToolResults `json:"tool_results"`
+
+	// LogLevel is the minimum level of session log events: debug, info
+	// (the default), warn or error.
+	LogLevel string `json:"log_level,omitempty"`
+
+	Logs Logs `json:"logs"`
+
+	Redaction Redaction `json:"redaction"`
+
+	Tracing Tracing `json:"tracing"`
+
+	UI UI `json:"ui"`
+}
+
+// UI configures the terminal interface.
+type UI struct {
+	// MaxInputLines is how far the message input grows before it scrolls,
+	// by default 10.
+	MaxInputLines int `json:"max_input_lines,omitempty"`
+	// MarkdownStyle is the glamour style replies are rendered with: "dark",
+	// "light", "dracula", "notty" or the path of a JSON style file. By
+	// default it follows the terminal's background.
+	MarkdownStyle string `json:"markdown_style,omitempty"`
+}
+
+// HistoryFile returns the file the chat input history of the workspace at
+// root is kept in. Workspaces are told apart by a hash of the path.
+func HistoryFile(root string) string {
+	sum := sha256.Sum256([]byte(root))
+	return filepath.Join(StateDir(), "history", fmt.Sprintf("%s-%x.jsonl", filepath.Base(root), sum[:4]))
+}
+
+// InputLines returns MaxInputLines with the default applied.
+func (u UI) InputLines() int {
+	if u.MaxInputLines <= 0 {
+		return 10
+	}
+	return u.MaxInputLines
+}
+
+// Tracing configures the recording of spans for model requests, tool calls
+// and commands.
+type Tracing struct {
+	// Enabled writes each session's spans to a file in the Chrome trace
+	// event format, which chrome://tracing and ui.perfetto.dev open.
+	Enabled bool `json:"enabled,omitempty"`
+	// Dir is where trace files are written, by default the traces directory
+	// in StateDir.
+	Dir string `json:"dir,omitempty"`
+	// OTLPEndpoint sends spans to an OpenTelemetry collector over OTLP/HTTP,
+	// e.g. "http://localhost:4318". It defaults to the
+	// OTEL_EXPORTER_OTLP_ENDPOINT environment variable.
+	OTLPEndpoint string            `json:"otlp_endpoint,omitempty"`
+	OTLPHeaders  map[string]string `json:"otlp_headers,omitempty"`
+}
+
+// TraceDir returns the directory trace files are written to.
+func (t Tracing) TraceDir() string {
+	if t.Dir != "" {
+		return t.Dir
+	}
+	return filepath.Join(StateDir(), "traces")
+}
+
+// Endpoint returns the OTLP endpoint spans are sent to, if any.
+func (t Tracing) Endpoint() string {
+	if t.OTLPEndpoint != "" {
+		return t.OTLPEndpoint
+	}
+	return os.Getenv("OTEL_EXPORTER_OTLP_ENDPOINT")
+}
+
+// Redaction configures the masking of secrets. Session logs are always
+// redacted; tool results only if ToolResults is set.
+type Redaction struct {
+	// Patterns are additional regular expressions matching secrets. If a
+	// pattern has a capturing group, only the group is masked, e.g.
+	// "INTERNAL_TOKEN=(\\S+)".
+	Patterns []string `json:"patterns,omitempty"`
+	// DisableEntropy turns off masking of random-looking strings that no
+	// specific detector recognises.
+	DisableEntropy bool `json:"disable_entropy,omitempty"`
+	// ToolResults also masks secrets in tool results before they are sent
+	// to the model.
+	ToolResults bool `json:"tool_results,omitempty"`
+}
+
+// Logs configures where session logs are kept and for how long. Zero values
+// select the defaults; negative values disable a limit.
+type Logs struct {
+	// Dir defaults to the logs directory in StateDir.
+	Dir string `json:"dir,omitempty"`
+	// MaxFileMB and MaxFileHours start a new part of a session's log once
+	// the current part grows beyond them (defaults 10 MB and 24 hours).
+	MaxFileMB    int `json:"max_file_mb,omitempty"`
+	MaxFileHours int `json:"max_file_hours,omitempty"`
+	// MaxAgeDays and MaxSessions bound how many old sessions are kept
+	// (defaults 30 days and 200 sessions).
+	MaxAgeDays  int `json:"max_age_days,omitempty"`
+	MaxSessions int `json:"max_sessions,omitempty"`
+}
+
+// LogDir returns the directory session logs are written to.
+func (l Logs) LogDir() string {
+	if l.Dir != "" {
+		return l.Dir
+	}
+	return filepath.Join(StateDir(), "logs")
+}
+
+// Limits returns the rotation and retention limits with defaults applied;
+// zero means no limit.
+func (l Logs) Limits() (maxFileBytes int64, maxFileAge time.Duration, maxAge time.Duration, maxSessions int) {
+	orDefault := func(value, def int) int {
+		switch {
+		case value == 0:
+			return def
+		case value < 0:
+			return 0
+		}
+		return value
+	}
+	return int64(orDefault(l.MaxFileMB, 10)) << 20,
+		time.Duration(orDefault(l.MaxFileHours, 24)) * time.Hour,
+		time.Duration(orDefault(l.MaxAgeDays, 30)) * 24 * time.Hour,
+		orDefault(l.MaxSessions, 200)
+}
+
+// ToolResults limits the size of tool results sent to the model. Larger
+// results are truncated and the full output is saved to a scratch file.
+type ToolResults struct {
+	// MaxBytes is the default limit; zero keeps the built-in default and a
+	// negative value disables truncation.
+	MaxBytes int `json:"max_bytes,omitempty"`
+	// PerTool overrides MaxBytes by tool name, e.g. {"go_check": 200000}.
+	PerTool map[string]int `json:"per_tool,omitempty"`
+}
+
+// Formatter is an external command that reads a file's content on stdin and
+// writes the formatted content to stdout, e.g.
+// ["prettier", "--stdin-filepath", "{path}"]. The argument "{path}" is
+// replaced by the path of the file being formatted.
+type Formatter struct {
+	Extensions []string `json:"extensions"`
+	Command    []string `json:"command"`
+}
+
+// LanguageServer describes a language server spoken to over stdio.
+type LanguageServer struct {
+	Name string `json:"name"`
+	// Command is the executable and its arguments, e.g. ["gopls"] or
+	// ["pyright-langserver", "--stdio"].
+	Command []string `json:"command"`
+	// Extensions lists the file extensions the server handles, e.g. [".py"].
+	Extensions []string `json:"extensions"`
+	// LanguageID overrides the LSP language identifier, which otherwise is
+	// derived from the file extension.
+	LanguageID string `json:"language_id,omitempty"`
+	// InitializationOptions is passed verbatim in the initialize request.
+	InitializationOptions json.RawMessage `json:"initialization_options,omitempty"`
+}
+
+// MCPServer describes a Model Context Protocol server. Exactly one of
+// Command (stdio transport) and URL (streamable HTTP transport) is set.
+type MCPServer struct {
+	// Name namespaces the server's tools, which are exposed to the model as
+	// mcp__<name>__<tool>.
+	Name    string            `json:"name"`
+	Command []string          `json:"command,omitempty"`
+	Env     map[string]string `json:"env,omitempty"`
+	URL     string            `json:"url,omitempty"`
+	// Headers are sent with every HTTP request, e.g. for authorization.
+	Headers map[string]string `json:"headers,omitempty"`
+	// Disabled keeps the server configured without connecting to it.
+	Disabled bool `json:"disabled,omitempty"`
+}
+
+// DefaultReadDeny lists files that commonly hold secrets, which read_file
+// refuses unless they match Permissions.AllowRead.
+var DefaultReadDeny = []string{
+	".env", ".env.*", "*.pem", "*.key", "*.p12", "*.pfx", "*.keystore",
+	"id_rsa", "id_dsa", "id_ecdsa", "id_ed25519",
+	".netrc", ".pgpass", ".git-credentials", ".npmrc", ".pypirc",
+}
+
+// defaultReadAllow exempts templates of secret files from DefaultReadDeny.
+var defaultReadAllow = []string{".env.example", ".env.sample", ".env.template", ".env.dist"}
+
+// Permissions restricts which files tools may read and modify.
+type Permissions struct {
+	// Deny lists rules of the form "action" or "action:pattern", where action
+	// is one of read, write, move, delete, mkdir or "*", and pattern is a path
+	// glob relative to the workspace root. A pattern ending in "/**" matches
+	// everything below that directory. "*" matches every action but read.
+	// Rules in the workspace configuration add to those of the user.
+	Deny []string `json:"deny"`
+	// AllowRead lists path globs that may be read even though they match
+	// DefaultReadDeny. It is only read from the user configuration, so that
+	// a repository cannot expose its own secret files.
+	AllowRead []string `json:"allow_read,omitempty"`
+}
+
+// Dir returns the per-user configuration directory.
+func Dir() string {
+	base := os.Getenv("XDG_CONFIG_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return filepath.Join(WorkspaceDir, "config")
+		}
+		base = filepath.Join(home, ".config")
+	}
+	return filepath.Join(base, AppName)
+}
+
+// StateDir returns the per-user directory for data such as logs, following
+// the XDG base directory specification.
+func StateDir() string {
+	base := os.Getenv("XDG_STATE_HOME")
+	if base == "" {
+		home, err := os.UserHomeDir()
+		if err != nil {
+			return filepath.Join(WorkspaceDir, "state")
+		}
+		base = filepath.Join(home, ".local", "state")
+	}
+	return filepath.Join(base, AppName)
+}
+
+// ToolDirs returns the directories searched for external tool manifests: the
+// per-user directory followed by the workspace directory, if the workspace
+// is trusted.
+func (c *Config) ToolDirs() []string {
+	dirs := []string{filepath.Join(Dir(), "tools")}
+	if c.workspaceTrusted {
+		dirs = append(dirs, filepath.Join(WorkspaceDir, "tools"))
+	}
+	return dirs
+}
+
+// trustedOnly are the settings that make the agent run commands, or send or
+// write session data somewhere else. A cloned repository must not be able to
+// choose them, so they are only taken from the workspace configuration if the
+// user trusts the workspace.
+var trustedOnly = []string{
+	"formatters", "language_servers", "mcp_servers",
+	"tracing.otlp_endpoint", "tracing.otlp_headers", "tracing.dir", "logs.dir",
+}
+
+// userOnly are the settings never taken from the workspace configuration.
+var userOnly = []string{"permissions.allow_read", "trusted_workspaces"}
+
+// Load reads the user configuration followed by the workspace configuration
+// in .agent/config.json. Fields set in the workspace file replace those from
+// the user file, except for the settings in userOnly, and those in
+// trustedOnly unless the workspace is trusted; Ignored lists the ones left
+// out. Deny rules from the workspace file are added to the user's. Missing
+// files are not an error.
+func Load() (*Config, error) {
+	cfg := &Config{}
+	userPath := filepath.Join(Dir(), "config.json")
+	if _, err := readConfig(userPath, cfg, nil); err != nil {
+		return nil, err
+	}
+
+	omit := map[string]string{}
+	for _, key := range userOnly {
+		omit[key] = "only the user configuration may set it"
+	}
+	cfg.workspaceTrusted = cfg.trusts(".")
+	untrusted := ""
+	if !cfg.workspaceTrusted {
+		wd, _ := filepath.Abs(".")
+		untrusted = fmt.Sprintf("the workspace is not trusted; add %q to trusted_workspaces in %s to use it", wd, userPath)
+		for _, key := range trustedOnly {
+			omit[key] = untrusted
+		}
+	}
+	// The workspace may add deny rules but not drop the user's.
+	userDeny := cfg.Permissions.Deny
+	cfg.Permissions.Deny = nil
+	ignored, err := readConfig(filepath.Join(WorkspaceDir, "config.json"), cfg, omit)
+	if err != nil {
+		return nil, err
+	}
+	cfg.Permissions.Deny = append(userDeny, cfg.Permissions.Deny...)
+	toolDir := filepath.Join(WorkspaceDir, "tools")
+	if _, err := os.Stat(toolDir); err == nil && !cfg.workspaceTrusted {
+		ignored = append(ignored, fmt.Sprintf("ignoring the tools in %s: %s", toolDir, untrusted))
+	}
+	cfg.Ignored = ignored
+	return cfg, nil
+}
+
+// readConfig reads the configuration file at path into cfg, if it exists,
+// leaving out the settings in omit, which maps a dotted JSON key such as
+// "permissions.allow_read" to why it is left out. It returns a note for
+// each setting left out.
+func readConfig(path string, cfg *Config, omit map[string]string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	var notes []string
+	if len(omit) > 0 {
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(data, &fields); err != nil {
+			return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+		}
+		for key, reason := range omit {
+			if removeKey(fields, key) {
+				notes = append(notes, fmt.Sprintf("%s: ignoring %s: %s", path, key, reason))
+			}
+		}
+		sort.Strings(notes)
+		if data, err = json.Marshal(fields); err != nil {
+			return nil, err
+		}
+	}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return notes, nil
+}
+
+// removeKey deletes the dotted key from fields and reports whether it was
+// set.
+func removeKey(fields map[string]json.RawMessage, key string) bool {
+	first, rest, nested := strings.Cut(key, ".")
+	raw, ok := fields[first]
+	if !ok {
+		return false
+	}
+	if !nested {
+		delete(fields, first)
+		return true
+	}
+	var inner map[string]json.RawMessage
+	if json.Unmarshal(raw, &inner) != nil || !removeKey(inner, rest) {
+		return false
+	}
+	fields[first], _ = json.Marshal(inner)
+	return true
+}
+
+// trusts reports whether the user trusts the workspace in dir.
+func (c *Config) trusts(dir string) bool {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return false
+	}
+	if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+		dir = resolved
+	}
+	for _, trusted := range c.TrustedWorkspaces {
+		if resolved, err := filepath.EvalSymlinks(trusted); err == nil {
+			trusted = resolved
+		}
+		if filepath.IsAbs(trusted) && filepath.Clean(trusted) == dir {
+			return true
+		}
+	}
+	return false
+}
+
+// LoadFile reads the configuration in the file at path only.
+func LoadFile(path string) (*Config, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read config: %w", err)
+	}
+	cfg := &Config{}
+	if err := json.Unmarshal(data, cfg); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Check returns an error if any deny rule matches action on path, where path
+// is relative to the workspace root.
+func (p Permissions) Check(action, path string) error {
+	path = filepath.ToSlash(path)
+	if action == "read" && matchAny(DefaultReadDeny, path) &&
+		!matchAny(defaultReadAllow, path) && !matchAny(p.AllowRead, path) {
+		return fmt.Errorf("permission denied: %s may contain secrets; add it to permissions.allow_read in the user configuration to let tools read it", path)
+	}
+	for _, rule := range p.Deny {
+		ruleAction, pattern, hasPattern := strings.Cut(rule, ":")
+		if ruleAction != action && (ruleAction != "*" || action == "read") {
+			continue
+		}
+		if !hasPattern || matchPath(pattern, path) {
+			return fmt.Errorf("permission denied: %s %s is blocked by rule %q", action, path, rule)
+		}
+	}
+	return nil
+}
+
+// matchAny reports whether path matches any of the glob patterns.
+func matchAny(patterns []string, path string) bool {
+	for _, pattern := range patterns {
+		if matchPath(pattern, path) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchPath reports whether path matches the glob pattern, either in full or
+// by its base name.
+func matchPath(pattern, path string) bool {
+	if prefix, ok := strings.CutSuffix(pattern, "/**"); ok {
+		return path == prefix || strings.HasPrefix(path, prefix+"/")
+	}
+	if ok, _ := filepath.Match(pattern, path); ok {
+		return true
+	}
+	ok, _ := filepath.Match(pattern, filepath.Base(path))
+	return ok
+}
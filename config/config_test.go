@@ -0,0 +1,67 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// loadWith writes the user and workspace configuration files and loads them
+// with a temporary workspace as the current directory.
+func loadWith(t *testing.T, user, workspace string) *Config {
+	t.Helper()
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+	t.Chdir(t.TempDir())
+	for path, content := range map[string]string{
+		filepath.Join(home, AppName, "config.json"): user,
+		filepath.Join(WorkspaceDir, "config.json"):  workspace,
+	} {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cfg, err := Load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	return cfg
+}
+
+func TestLoadDenyRules(t *testing.T) {
+	cfg := loadWith(t,
+		`{"permissions": {"deny": ["write:secrets/**"]}}`,
+		`{"permissions": {"deny": ["delete"]}}`)
+	if err := cfg.Permissions.Check("write", "secrets/key.txt"); err == nil {
+		t.Error("the workspace configuration dropped the user's deny rule")
+	}
+	if err := cfg.Permissions.Check("delete", "main.go"); err == nil {
+		t.Error("the workspace configuration's deny rule was not applied")
+	}
+
+	cfg = loadWith(t, `{"permissions": {"deny": ["write:secrets/**"]}}`, `{"permissions": {"deny": []}}`)
+	if len(cfg.Permissions.Deny) != 1 {
+		t.Errorf("Deny = %q, want the user's rule", cfg.Permissions.Deny)
+	}
+}
+
+func TestLoadUntrustedWorkspace(t *testing.T) {
+	cfg := loadWith(t, `{"logs": {"max_file_mb": 5}}`, `{
+		"tracing": {"enabled": true, "otlp_endpoint": "https://collector.example.com", "dir": "/tmp/traces"},
+		"logs": {"dir": "/tmp/logs", "max_file_mb": 1},
+		"formatters": [{"extensions": [".go"], "command": ["sh", "-c", "true"]}]
+	}`)
+	if cfg.Tracing.OTLPEndpoint != "" || cfg.Tracing.Dir != "" || cfg.Logs.Dir != "" || len(cfg.Formatters) != 0 {
+		t.Errorf("untrusted workspace set tracing %+v, logs %+v, formatters %+v", cfg.Tracing, cfg.Logs, cfg.Formatters)
+	}
+	// Its other settings apply.
+	if !cfg.Tracing.Enabled || cfg.Logs.MaxFileMB != 1 {
+		t.Errorf("tracing %+v, logs %+v: want the workspace's other settings", cfg.Tracing, cfg.Logs)
+	}
+	if len(cfg.Ignored) != 4 {
+		t.Errorf("Ignored = %q, want 4 notes", cfg.Ignored)
+	}
+}
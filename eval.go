@@ -0,0 +1,145 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"os"
+	"regexp"
+	"time"
+
+	"agent/agent"
+	"agent/config"
+	"agent/eval"
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+const evalUsage = `usage: agent eval <command>
+
+commands:
+  run [-suite dir] [-run regexp] [-config file] [-provider auto|real|scripted] [-o report.json] [-baseline report.json] [-keep]
+      run the tasks of a suite and report the results
+  compare <a.json> <b.json>
+      compare two saved reports, e.g. of two configurations or builds`
+
+// evalCommand runs evaluation suites and compares their reports.
+func evalCommand(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", evalUsage)
+	}
+	switch args[0] {
+	case "run":
+		return evalRun(cfg, args[1:])
+	case "compare":
+		if len(args) != 3 {
+			return fmt.Errorf("usage: agent eval compare <a.json> <b.json>")
+		}
+		a, err := eval.LoadReport(args[1])
+		if err != nil {
+			return err
+		}
+		b, err := eval.LoadReport(args[2])
+		if err != nil {
+			return err
+		}
+		return eval.Compare(os.Stdout, a, b)
+	}
+	return fmt.Errorf("unknown eval command %q\n%s", args[0], evalUsage)
+}
+
+func evalRun(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("eval run", flag.ExitOnError)
+	suite := flags.String("suite", "evals", "directory of task fixtures")
+	filter := flags.String("run", "", "only run tasks whose names match this regular expression")
+	configFile := flags.String("config", "", "configuration file to run the agent with instead of the usual ones")
+	providerName := flags.String("provider", "auto", "model to use: real, scripted, or auto to use a task's script if it has one")
+	output := flags.String("o", "", "save the report as JSON to this file")
+	baseline := flags.String("baseline", "", "compare the results with this saved report")
+	keep := flags.Bool("keep", false, "keep each task's working copy")
+	label := flags.String("label", "", "name of this run in reports (default the config file)")
+	flags.Parse(args)
+
+	var match *regexp.Regexp
+	if *filter != "" {
+		var err error
+		if match, err = regexp.Compile(*filter); err != nil {
+			return fmt.Errorf("invalid -run pattern: %w", err)
+		}
+	}
+	if *configFile != "" {
+		var err error
+		if cfg, err = config.LoadFile(*configFile); err != nil {
+			return err
+		}
+	}
+	tasks, err := eval.LoadSuite(*suite, match)
+	if err != nil {
+		return err
+	}
+	if len(tasks) == 0 {
+		return fmt.Errorf("no tasks found in %s", *suite)
+	}
+
+	client := anthropic.NewClient()
+	anthropicModel := agent.AnthropicProvider(&client)
+	chooseProvider := func(task *eval.Task) (agent.Provider, error) {
+		switch *providerName {
+		case "real":
+			return anthropicModel, nil
+		case "scripted":
+			return task.LoadScript()
+		case "auto":
+			if task.HasScript() {
+				return task.LoadScript()
+			}
+			return anthropicModel, nil
+		}
+		return nil, fmt.Errorf("unknown provider %q", *providerName)
+	}
+
+	// MCP servers are left out, as they would act outside the task's copy.
+	env := setup(cfg, &client, false)
+	defer env.Close()
+	runner := &eval.Runner{Agent: env.agent, Provider: chooseProvider, Keep: *keep}
+
+	report := &eval.Report{Label: *label, Provider: *providerName, Started: time.Now()}
+	if report.Label == "" {
+		report.Label = *configFile
+		if report.Label == "" {
+			report.Label = "default config"
+		}
+	}
+	for i, task := range tasks {
+		fmt.Printf("[%d/%d] %s … ", i+1, len(tasks), task.Name)
+		result := runner.Run(context.Background(), task)
+		status := "pass"
+		if !result.Passed {
+			status = "FAIL"
+		}
+		fmt.Printf("%s (%d turns, %s)\n", status, result.Turns, time.Duration(result.DurationMS)*time.Millisecond)
+		if result.Workspace != "" {
+			fmt.Printf("      kept in %s\n", result.Workspace)
+		}
+		report.Results = append(report.Results, result)
+	}
+
+	fmt.Println()
+	if err := eval.WriteText(os.Stdout, report); err != nil {
+		return err
+	}
+	if *output != "" {
+		if err := report.Save(*output); err != nil {
+			return err
+		}
+		fmt.Printf("\nSaved the report to %s\n", *output)
+	}
+	if *baseline != "" {
+		base, err := eval.LoadReport(*baseline)
+		if err != nil {
+			return err
+		}
+		fmt.Println()
+		return eval.Compare(os.Stdout, base, report)
+	}
+	return nil
+}
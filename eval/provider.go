@@ -0,0 +1,93 @@
+package eval
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"agent/agent"
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// ErrScriptExhausted is returned when the agent makes more model requests
+// than a script has responses.
+var ErrScriptExhausted = errors.New("the script has no more responses")
+
+// ScriptedProvider returns canned model responses in order, regardless of
+// the request, so that tasks run without network access and
+// deterministically.
+type ScriptedProvider struct {
+	mu        sync.Mutex
+	responses []json.RawMessage
+}
+
+// LoadScript reads the scripted responses of a task: a JSON array of
+// messages as returned by the Messages API, of which only "content" is
+// required, e.g.
+//
+//	[{"content": [{"type": "tool_use", "id": "1", "name": "read_file", "input": {"path": "main.go"}}]},
+//	 {"content": [{"type": "text", "text": "Done."}]}]
+func (t *Task) LoadScript() (*ScriptedProvider, error) {
+	data, err := os.ReadFile(filepath.Join(t.Dir, "script.json"))
+	if err != nil {
+		return nil, err
+	}
+	var responses []json.RawMessage
+	if err := json.Unmarshal(data, &responses); err != nil {
+		return nil, fmt.Errorf("failed to parse script of task %s: %w", t.Name, err)
+	}
+	return &ScriptedProvider{responses: responses}, nil
+}
+
+// NewMessage returns the next scripted response.
+func (p *ScriptedProvider) NewMessage(ctx context.Context, params anthropic.MessageNewParams) (*anthropic.Message, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if len(p.responses) == 0 {
+		return nil, ErrScriptExhausted
+	}
+	var message anthropic.Message
+	if err := json.Unmarshal(p.responses[0], &message); err != nil {
+		return nil, fmt.Errorf("invalid scripted response: %w", err)
+	}
+	p.responses = p.responses[1:]
+	if message.Role == "" {
+		message.Role = "assistant"
+	}
+	return &message, nil
+}
+
+// CountingProvider passes requests to Provider and counts them and the
+// tokens used.
+type CountingProvider struct {
+	Provider agent.Provider
+
+	mu           sync.Mutex
+	requests     int
+	inputTokens  int64
+	outputTokens int64
+}
+
+// NewMessage forwards the request and counts it.
+func (p *CountingProvider) NewMessage(ctx context.Context, params anthropic.MessageNewParams) (*anthropic.Message, error) {
+	message, err := p.Provider.NewMessage(ctx, params)
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.requests++
+	if err == nil {
+		p.inputTokens += message.Usage.InputTokens
+		p.outputTokens += message.Usage.OutputTokens
+	}
+	return message, err
+}
+
+// Counts returns the number of requests made and the tokens used.
+func (p *CountingProvider) Counts() (requests int, inputTokens, outputTokens int64) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.requests, p.inputTokens, p.outputTokens
+}
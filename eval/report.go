@@ -0,0 +1,177 @@
+package eval
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+)
+
+// Report is the outcome of running a suite with one configuration.
+type Report struct {
+	// Label names the configuration, by default the config file used.
+	Label    string    `json:"label"`
+	Provider string    `json:"provider"`
+	Started  time.Time `json:"started"`
+	Results  []Result  `json:"results"`
+}
+
+// Totals sums up the results.
+type Totals struct {
+	Tasks, Passed             int
+	Turns                     int
+	InputTokens, OutputTokens int64
+	Duration                  time.Duration
+}
+
+// Totals returns the sums over all results.
+func (r *Report) Totals() Totals {
+	t := Totals{Tasks: len(r.Results)}
+	for _, res := range r.Results {
+		if res.Passed {
+			t.Passed++
+		}
+		t.Turns += res.Turns
+		t.InputTokens += res.InputTokens
+		t.OutputTokens += res.OutputTokens
+		t.Duration += time.Duration(res.DurationMS) * time.Millisecond
+	}
+	return t
+}
+
+// result returns the result of the named task, if r has one.
+func (r *Report) result(task string) (Result, bool) {
+	for _, res := range r.Results {
+		if res.Task == task {
+			return res, true
+		}
+	}
+	return Result{}, false
+}
+
+// Save writes r as JSON to path.
+func (r *Report) Save(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, append(data, '\n'), 0o644)
+}
+
+// LoadReport reads a report saved with Save.
+func LoadReport(path string) (*Report, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	r := &Report{}
+	if err := json.Unmarshal(data, r); err != nil {
+		return nil, fmt.Errorf("failed to parse report %s: %w", path, err)
+	}
+	if r.Label == "" {
+		r.Label = path
+	}
+	return r, nil
+}
+
+// WriteText writes a table of the results followed by the totals.
+func WriteText(w io.Writer, r *Report) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TASK\tRESULT\tTURNS\tTOKENS IN\tTOKENS OUT\tTIME\tNOTE")
+	for _, res := range r.Results {
+		note := res.Error
+		if note == "" && !res.Passed {
+			note = summarize(res.CheckOutput)
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%d\t%d\t%d\t%s\t%s\n", res.Task, passFail(res.Passed), res.Turns,
+			res.InputTokens, res.OutputTokens, formatMS(res.DurationMS), note)
+	}
+	t := r.Totals()
+	fmt.Fprintf(tw, "TOTAL\t%d/%d\t%d\t%d\t%d\t%s\t\n", t.Passed, t.Tasks, t.Turns,
+		t.InputTokens, t.OutputTokens, t.Duration.Round(100*time.Millisecond))
+	return tw.Flush()
+}
+
+// Compare writes a table comparing two reports task by task, with the
+// change in each measure from a to b.
+func Compare(w io.Writer, a, b *Report) error {
+	fmt.Fprintf(w, "A: %s (%s, %s)\nB: %s (%s, %s)\n\n",
+		a.Label, a.Provider, a.Started.Format(time.DateTime), b.Label, b.Provider, b.Started.Format(time.DateTime))
+
+	var tasks []string
+	seen := make(map[string]bool)
+	for _, r := range []*Report{a, b} {
+		for _, res := range r.Results {
+			if !seen[res.Task] {
+				seen[res.Task] = true
+				tasks = append(tasks, res.Task)
+			}
+		}
+	}
+
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(tw, "TASK\tA\tB\tTURNS\tTOKENS\tTIME")
+	for _, task := range tasks {
+		ra, okA := a.result(task)
+		rb, okB := b.result(task)
+		if !okA || !okB {
+			fmt.Fprintf(tw, "%s\t%s\t%s\t\t\t\n", task, outcome(ra, okA), outcome(rb, okB))
+			continue
+		}
+		fmt.Fprintf(tw, "%s\t%s\t%s\t%s\t%s\t%s\n", task, outcome(ra, true), outcome(rb, true),
+			delta(int64(ra.Turns), int64(rb.Turns)),
+			delta(ra.InputTokens+ra.OutputTokens, rb.InputTokens+rb.OutputTokens),
+			deltaMS(ra.DurationMS, rb.DurationMS))
+	}
+	ta, tb := a.Totals(), b.Totals()
+	fmt.Fprintf(tw, "TOTAL\t%d/%d\t%d/%d\t%s\t%s\t%s\n", ta.Passed, ta.Tasks, tb.Passed, tb.Tasks,
+		delta(int64(ta.Turns), int64(tb.Turns)),
+		delta(ta.InputTokens+ta.OutputTokens, tb.InputTokens+tb.OutputTokens),
+		deltaMS(ta.Duration.Milliseconds(), tb.Duration.Milliseconds()))
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+
+	// Call out the tasks whose outcome changed.
+	for _, task := range tasks {
+		ra, okA := a.result(task)
+		rb, okB := b.result(task)
+		if okA && okB && ra.Passed != rb.Passed {
+			if rb.Passed {
+				fmt.Fprintf(w, "\nfixed: %s", task)
+			} else {
+				fmt.Fprintf(w, "\nbroken: %s", task)
+			}
+		}
+	}
+	fmt.Fprintln(w)
+	return nil
+}
+
+func passFail(passed bool) string {
+	if passed {
+		return "pass"
+	}
+	return "FAIL"
+}
+
+func outcome(res Result, ok bool) string {
+	if !ok {
+		return "-"
+	}
+	return passFail(res.Passed)
+}
+
+func delta(a, b int64) string {
+	return fmt.Sprintf("%d → %d (%+d)", a, b, b-a)
+}
+
+func deltaMS(a, b int64) string {
+	return fmt.Sprintf("%s → %s (%+.1fs)", formatMS(a), formatMS(b), float64(b-a)/1000)
+}
+
+func formatMS(ms int64) string {
+	return (time.Duration(ms) * time.Millisecond).Round(100 * time.Millisecond).String()
+}
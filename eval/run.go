@@ -0,0 +1,117 @@
+package eval
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"time"
+
+	"agent/agent"
+	"agent/tools"
+)
+
+// Result is the outcome of one task.
+type Result struct {
+	Task         string `json:"task"`
+	Passed       bool   `json:"passed"`
+	Error        string `json:"error,omitempty"` // why the agent or the check failed to run
+	Turns        int    `json:"turns"`           // model requests made
+	InputTokens  int64  `json:"input_tokens"`
+	OutputTokens int64  `json:"output_tokens"`
+	DurationMS   int64  `json:"duration_ms"`
+	Answer       string `json:"answer,omitempty"`
+	CheckOutput  string `json:"check_output,omitempty"`
+	Workspace    string `json:"workspace,omitempty"` // set if the copy was kept
+}
+
+// Runner runs tasks with an agent, one at a time, since the agent's tools
+// work in the process's current directory.
+type Runner struct {
+	Agent *agent.Agent
+	// Provider returns the model a task is run against.
+	Provider func(*Task) (agent.Provider, error)
+	// Keep leaves each task's copy of its repo in place for inspection.
+	Keep bool
+}
+
+// Run runs task in a fresh copy of its repo and checks the outcome.
+func (r *Runner) Run(ctx context.Context, task *Task) Result {
+	result := Result{Task: task.Name}
+	provider, err := r.Provider(task)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+
+	work, err := task.Prepare()
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	if r.Keep {
+		result.Workspace = work
+	} else {
+		defer os.RemoveAll(work)
+	}
+	restore, err := enterWorkspace(work)
+	if err != nil {
+		result.Error = err.Error()
+		return result
+	}
+	defer restore()
+
+	counter := &CountingProvider{Provider: provider}
+	r.Agent.Provider = counter
+	started := time.Now()
+	taskCtx, cancel := context.WithTimeout(ctx, task.TimeLimit())
+	answer, err := r.Agent.RunTask(taskCtx, task.Instruction)
+	cancel()
+	result.DurationMS = time.Since(started).Milliseconds()
+	result.Turns, result.InputTokens, result.OutputTokens = counter.Counts()
+	result.Answer = answer
+	if err != nil {
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("timed out after %s", task.TimeLimit())
+		}
+		result.Error = "agent: " + err.Error()
+	}
+
+	// The check runs even if the agent failed, since it may have finished
+	// the work before failing.
+	passed, output, err := task.Verify(ctx, work)
+	result.Passed, result.CheckOutput = passed, output
+	if err != nil && result.Error == "" {
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// enterWorkspace makes dir the current directory and the tools' workspace,
+// with no files read and no changes to undo, and returns a function that
+// restores the previous workspace.
+func enterWorkspace(dir string) (func(), error) {
+	previous, err := os.Getwd()
+	if err != nil {
+		return nil, err
+	}
+	previousRoot := tools.WorkspaceRoot()
+	if err := os.Chdir(dir); err != nil {
+		return nil, err
+	}
+	reset := func() {
+		tools.ResetFileTracker()
+		tools.ResetCheckpoints()
+		tools.TakeChangedFiles()
+	}
+	if err := tools.SetWorkspaceRoot(dir); err != nil {
+		os.Chdir(previous)
+		return nil, err
+	}
+	reset()
+	return func() {
+		reset()
+		os.Chdir(previous)
+		tools.SetWorkspaceRoot(previousRoot)
+	}, nil
+}
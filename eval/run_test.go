@@ -0,0 +1,80 @@
+package eval
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"agent/agent"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// TestRunRelativeSuite checks that the check files of a suite given by a
+// relative path are used, although the agent works in another directory.
+func TestRunRelativeSuite(t *testing.T) {
+	t.Chdir(t.TempDir())
+	writeFiles(t, "suite/hidden", map[string]string{
+		"task.json":      `{"instruction": "Do nothing.", "check": ["sh", "check.sh"]}`,
+		"script.json":    `[{"content": [{"type": "text", "text": "Done."}]}]`,
+		"repo/check.sh":  "echo visible check; exit 0\n",
+		"check/check.sh": "echo hidden check; exit 1\n",
+	})
+
+	tasks, err := LoadSuite("suite", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(tasks) != 1 {
+		t.Fatalf("loaded %d tasks, want 1", len(tasks))
+	}
+	runner := &Runner{
+		Agent: agent.NewAgent(nil, nil, nil),
+		Provider: func(task *Task) (agent.Provider, error) {
+			return task.LoadScript()
+		},
+	}
+	result := runner.Run(context.Background(), tasks[0])
+	if result.Error != "" {
+		t.Fatalf("run failed: %s", result.Error)
+	}
+	if result.Passed || !strings.Contains(result.CheckOutput, "hidden check") {
+		t.Errorf("passed = %v with output %q, want the hidden check to fail", result.Passed, result.CheckOutput)
+	}
+}
+
+func TestVerifyUnreadableCheckFiles(t *testing.T) {
+	if os.Getuid() == 0 {
+		t.Skip("permissions are not enforced for root")
+	}
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"task/task.json":       `{"instruction": "x", "check": ["true"]}`,
+		"task/check/check.txt": "x",
+	})
+	task, err := LoadTask(filepath.Join(dir, "task"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The directory can be listed but not entered.
+	if err := os.Chmod(filepath.Join(dir, "task"), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	defer os.Chmod(filepath.Join(dir, "task"), 0o755)
+	if _, _, err := task.Verify(context.Background(), t.TempDir()); err == nil {
+		t.Error("Verify succeeded with unreadable check files")
+	}
+}
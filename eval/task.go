@@ -0,0 +1,230 @@
+// Package eval runs the agent on a suite of task fixtures and reports how
+// well it did, so that changes to prompts and tools can be compared.
+//
+// A suite is a directory with one subdirectory per task:
+//
+//	<task>/task.json    the instruction and the check, see Task
+//	<task>/repo/        the starting directory, copied for every run
+//	<task>/check/       files copied into the copy before the check runs,
+//	                    such as hidden tests (optional)
+//	<task>/script.json  scripted model responses (optional)
+package eval
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	defaultTimeout      = 10 * time.Minute
+	defaultCheckTimeout = 5 * time.Minute
+	// maxCheckOutput bounds the check output kept in reports.
+	maxCheckOutput = 4000
+)
+
+// Task is a task fixture.
+type Task struct {
+	Name string `json:"-"`
+	Dir  string `json:"-"` // absolute
+
+	// Instruction is given to the agent as its prompt.
+	Instruction string `json:"instruction"`
+	// Check is the command verifying the result, run in the task's copy of
+	// the repo, e.g. ["go", "test", "./..."] or ["sh", "-c", "grep -q x f"].
+	// The task passes if it exits with status 0.
+	Check []string `json:"check"`
+	// Timeout bounds the agent's work and CheckTimeout the check, as Go
+	// durations such as "10m".
+	Timeout      string `json:"timeout,omitempty"`
+	CheckTimeout string `json:"check_timeout,omitempty"`
+
+	timeout, checkTimeout time.Duration
+}
+
+// LoadSuite reads the tasks in dir whose names match filter, if given, in
+// name order.
+func LoadSuite(dir string, filter *regexp.Regexp) ([]*Task, error) {
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read suite: %w", err)
+	}
+	var tasks []*Task
+	for _, entry := range entries {
+		if !entry.IsDir() || (filter != nil && !filter.MatchString(entry.Name())) {
+			continue
+		}
+		path := filepath.Join(dir, entry.Name())
+		if _, err := os.Stat(filepath.Join(path, "task.json")); os.IsNotExist(err) {
+			continue
+		}
+		task, err := LoadTask(path)
+		if err != nil {
+			return nil, err
+		}
+		tasks = append(tasks, task)
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].Name < tasks[j].Name })
+	return tasks, nil
+}
+
+// LoadTask reads the task fixture in dir.
+func LoadTask(dir string) (*Task, error) {
+	// The fixture's files are read while the agent works in the task's copy
+	// of the repo, so they must not be found relative to the current
+	// directory.
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(filepath.Join(dir, "task.json"))
+	if err != nil {
+		return nil, err
+	}
+	task := &Task{Name: filepath.Base(dir), Dir: dir}
+	if err := json.Unmarshal(data, task); err != nil {
+		return nil, fmt.Errorf("failed to parse task %s: %w", task.Name, err)
+	}
+	if task.Instruction == "" || len(task.Check) == 0 {
+		return nil, fmt.Errorf("task %s needs an instruction and a check", task.Name)
+	}
+	if task.timeout, err = parseDuration(task.Timeout, defaultTimeout); err != nil {
+		return nil, fmt.Errorf("task %s: invalid timeout: %w", task.Name, err)
+	}
+	if task.checkTimeout, err = parseDuration(task.CheckTimeout, defaultCheckTimeout); err != nil {
+		return nil, fmt.Errorf("task %s: invalid check_timeout: %w", task.Name, err)
+	}
+	return task, nil
+}
+
+func parseDuration(s string, def time.Duration) (time.Duration, error) {
+	if s == "" {
+		return def, nil
+	}
+	return time.ParseDuration(s)
+}
+
+// TimeLimit returns how long the agent may work on the task.
+func (t *Task) TimeLimit() time.Duration {
+	return t.timeout
+}
+
+// Prepare copies the task's starting directory to a new temporary
+// directory and returns its path.
+func (t *Task) Prepare() (string, error) {
+	work, err := os.MkdirTemp("", "agent-eval-"+t.Name+"-")
+	if err != nil {
+		return "", err
+	}
+	repo := filepath.Join(t.Dir, "repo")
+	if _, err := os.Stat(repo); err == nil {
+		if err := copyDir(repo, work); err != nil {
+			os.RemoveAll(work)
+			return "", fmt.Errorf("failed to copy %s: %w", repo, err)
+		}
+	} else if !os.IsNotExist(err) {
+		os.RemoveAll(work)
+		return "", fmt.Errorf("failed to read %s: %w", repo, err)
+	}
+	return work, nil
+}
+
+// Verify copies the task's check files into work and runs the check there.
+// It returns whether the check passed, and its output.
+func (t *Task) Verify(ctx context.Context, work string) (bool, string, error) {
+	checkFiles := filepath.Join(t.Dir, "check")
+	if _, err := os.Stat(checkFiles); err == nil {
+		if err := copyDir(checkFiles, work); err != nil {
+			return false, "", fmt.Errorf("failed to copy check files: %w", err)
+		}
+	} else if !os.IsNotExist(err) {
+		return false, "", fmt.Errorf("failed to read check files: %w", err)
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, t.checkTimeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, t.Check[0], t.Check[1:]...)
+	cmd.Dir = work
+	var out bytes.Buffer
+	cmd.Stdout, cmd.Stderr = &out, &out
+	err := cmd.Run()
+	output := out.String()
+	if len(output) > maxCheckOutput {
+		output = "…" + output[len(output)-maxCheckOutput:]
+	}
+	if _, failed := err.(*exec.ExitError); failed && ctx.Err() == nil {
+		return false, output, nil
+	}
+	if err != nil {
+		return false, output, fmt.Errorf("failed to run check: %w", err)
+	}
+	return true, output, nil
+}
+
+// copyDir copies the files and symlinks below src into dst.
+func copyDir(src, dst string) error {
+	return filepath.WalkDir(src, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(dst, rel)
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		switch {
+		case d.IsDir():
+			return os.MkdirAll(target, info.Mode().Perm()|0o700)
+		case info.Mode()&fs.ModeSymlink != 0:
+			link, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			return os.Symlink(link, target)
+		}
+		return copyFile(path, target, info.Mode().Perm())
+	})
+}
+
+func copyFile(src, dst string, perm fs.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+// HasScript reports whether the task has scripted model responses.
+func (t *Task) HasScript() bool {
+	_, err := os.Stat(filepath.Join(t.Dir, "script.json"))
+	return err == nil
+}
+
+// summarize shortens a check's output to its last line, for tables.
+func summarize(output string) string {
+	lines := strings.Split(strings.TrimSpace(output), "\n")
+	return lines[len(lines)-1]
+}
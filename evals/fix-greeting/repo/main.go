@@ -0,0 +1,12 @@
+package main
+
+import "fmt"
+
+// Greeting returns the message printed by the program.
+func Greeting(name string) string {
+	return "Hi " + name
+}
+
+func main() {
+	fmt.Println(Greeting("world"))
+}
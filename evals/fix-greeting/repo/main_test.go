@@ -0,0 +1,9 @@
+package main
+
+import "testing"
+
+func TestGreeting(t *testing.T) {
+	if got, want := Greeting("world"), "Hello, world!"; got != want {
+		t.Errorf("Greeting(%q) = %q, want %q", "world", got, want)
+	}
+}
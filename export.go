@@ -0,0 +1,50 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+
+	"agent/config"
+	"agent/logger"
+	"agent/transcript"
+)
+
+// exportCommand renders a logged session as Markdown, HTML or JSON.
+func exportCommand(cfg *config.Config, args []string) error {
+	// Accept the session before or after the flags.
+	var id string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		id, args = args[0], args[1:]
+	}
+	flags := flag.NewFlagSet("export", flag.ExitOnError)
+	format := flags.String("format", "md", "output format: "+strings.Join(transcript.Formats, ", "))
+	output := flags.String("o", "", "output file, or - for standard output (default session_<id>.<format>)")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: agent export [session] [-format md|html|json] [-o file]\n\nThe session defaults to the latest; an ID prefix is enough.")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if id == "" {
+		id = flags.Arg(0)
+	}
+
+	session, err := logger.FindSession(cfg.Logs.LogDir(), id)
+	if err != nil {
+		return err
+	}
+	if *output == "-" {
+		t, err := transcript.Load(session)
+		if err != nil {
+			return err
+		}
+		return transcript.Write(os.Stdout, t, *format)
+	}
+	path, err := transcript.Export(session, *format, *output)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("Exported session %s to %s\n", session.ID, path)
+	return nil
+}
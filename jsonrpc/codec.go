@@ -0,0 +1,109 @@
+package jsonrpc
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Codec reads and writes whole JSON-RPC messages on a byte stream.
+type Codec interface {
+	Read() ([]byte, error)
+	Write(msg []byte) error
+}
+
+// headerCodec frames messages with a Content-Length header, as used by the
+// Language Server Protocol.
+type headerCodec struct {
+	r  *bufio.Reader
+	w  io.Writer
+	mu sync.Mutex
+}
+
+// NewHeaderCodec returns a codec that frames each message with HTTP-style
+// headers followed by a blank line.
+func NewHeaderCodec(r io.Reader, w io.Writer) Codec {
+	return &headerCodec{r: bufio.NewReader(r), w: w}
+}
+
+func (c *headerCodec) Read() ([]byte, error) {
+	length := -1
+	for {
+		line, err := c.r.ReadString('\n')
+		if err != nil {
+			return nil, err
+		}
+		line = strings.TrimRight(line, "\r\n")
+		if line == "" {
+			break
+		}
+		name, value, ok := strings.Cut(line, ":")
+		if !ok {
+			return nil, fmt.Errorf("malformed header line %q", line)
+		}
+		if strings.EqualFold(strings.TrimSpace(name), "Content-Length") {
+			length, err = strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				return nil, fmt.Errorf("invalid Content-Length %q", value)
+			}
+		}
+	}
+	if length < 0 {
+		return nil, fmt.Errorf("message without Content-Length header")
+	}
+
+	msg := make([]byte, length)
+	if _, err := io.ReadFull(c.r, msg); err != nil {
+		return nil, err
+	}
+	return msg, nil
+}
+
+func (c *headerCodec) Write(msg []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := fmt.Fprintf(c.w, "Content-Length: %d\r\n\r\n", len(msg)); err != nil {
+		return err
+	}
+	_, err := c.w.Write(msg)
+	return err
+}
+
+// lineCodec writes each message as a single line of JSON, as used by the
+// Model Context Protocol stdio transport.
+type lineCodec struct {
+	r  *bufio.Reader
+	w  io.Writer
+	mu sync.Mutex
+}
+
+// NewLineCodec returns a codec for newline-delimited JSON messages.
+func NewLineCodec(r io.Reader, w io.Writer) Codec {
+	return &lineCodec{r: bufio.NewReader(r), w: w}
+}
+
+func (c *lineCodec) Read() ([]byte, error) {
+	for {
+		line, err := c.r.ReadBytes('\n')
+		if len(strings.TrimSpace(string(line))) > 0 {
+			return line, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (c *lineCodec) Write(msg []byte) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if _, err := c.w.Write(append(msg, '\n')); err != nil {
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package jsonrpc
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+)
+
+// Standard JSON-RPC error codes.
+const (
+	CodeParseError     = -32700
+	CodeInvalidRequest = -32600
+	CodeMethodNotFound = -32601
+	CodeInvalidParams  = -32602
+	CodeInternalError  = -32603
+)
+
+// ErrClosed is returned by calls made on, or interrupted by, a closed connection.
+var ErrClosed = errors.New("jsonrpc: connection closed")
+
+// message is the union of JSON-RPC requests, notifications and responses.
+type message struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      json.RawMessage `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  json.RawMessage `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *Error          `json:"error,omitempty"`
+}
+
+// Error is a JSON-RPC error object.
+type Error struct {
+	Code    int             `json:"code"`
+	Message string          `json:"message"`
+	Data    json.RawMessage `json:"data,omitempty"`
+}
+
+func (e *Error) Error() string {
+	return fmt.Sprintf("jsonrpc error %d: %s", e.Code, e.Message)
+}
+
+// Handler serves requests and notifications sent by the peer. For
+// notifications the result is discarded. Returning an *Error sends it to the
+// peer unchanged; any other error is reported as an internal error.
+type Handler func(ctx context.Context, method string, params json.RawMessage) (any, error)
+
+// MethodNotFound is a Handler result for methods a handler does not serve.
+func MethodNotFound(method string) error {
+	return &Error{Code: CodeMethodNotFound, Message: "method not found: " + method}
+}
+
+// Conn is a bidirectional JSON-RPC 2.0 connection.
+type Conn struct {
+	codec   Codec
+	handler Handler
+
+	mu      sync.Mutex
+	nextID  int64
+	pending map[string]chan *message
+	err     error
+	done    chan struct{}
+
+	handlers sync.WaitGroup // incoming requests being handled
+}
+
+// NewConn starts reading messages from codec. Incoming requests and
+// notifications are passed to handler, which may be nil.
+func NewConn(codec Codec, handler Handler) *Conn {
+	c := &Conn{
+		codec:   codec,
+		handler: handler,
+		pending: make(map[string]chan *message),
+		done:    make(chan struct{}),
+	}
+	go c.readLoop()
+	return c
+}
+
+// Done is closed once the connection stops reading.
+func (c *Conn) Done() <-chan struct{} {
+	return c.done
+}
+
+// Err returns the error that stopped the connection, if any.
+func (c *Conn) Err() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.err
+}
+
+// Call sends a request and decodes its result into result, which may be nil.
+func (c *Conn) Call(ctx context.Context, method string, params, result any) error {
+	c.mu.Lock()
+	if c.err != nil {
+		c.mu.Unlock()
+		return c.err
+	}
+	c.nextID++
+	id := json.RawMessage(fmt.Sprint(c.nextID))
+	reply := make(chan *message, 1)
+	c.pending[string(id)] = reply
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.pending, string(id))
+		c.mu.Unlock()
+	}()
+
+	if err := c.send(&message{ID: id, Method: method}, params); err != nil {
+		return err
+	}
+
+	select {
+	case msg := <-reply:
+		if msg.Error != nil {
+			return msg.Error
+		}
+		if result != nil && len(msg.Result) > 0 {
+			return json.Unmarshal(msg.Result, result)
+		}
+		return nil
+	case <-c.done:
+		return c.Err()
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Notify sends a notification, which has no response.
+func (c *Conn) Notify(method string, params any) error {
+	return c.send(&message{Method: method}, params)
+}
+
+// send marshals params into msg and writes it.
+func (c *Conn) send(msg *message, params any) error {
+	msg.JSONRPC = "2.0"
+	if params != nil {
+		data, err := json.Marshal(params)
+		if err != nil {
+			return err
+		}
+		msg.Params = data
+	}
+	return c.write(msg)
+}
+
+func (c *Conn) write(msg *message) error {
+	data, err := json.Marshal(msg)
+	if err != nil {
+		return err
+	}
+	return c.codec.Write(data)
+}
+
+// Wait blocks until every incoming request read so far has been handled
+// and answered. Responses can still be written after the peer closes its
+// end of the stream.
+func (c *Conn) Wait() {
+	c.handlers.Wait()
+}
+
+// Close stops the connection; pending calls return ErrClosed. It does not
+// close the underlying stream.
+func (c *Conn) Close() error {
+	c.shutdown(ErrClosed)
+	return nil
+}
+
+func (c *Conn) shutdown(err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.err == nil {
+		c.err = err
+		close(c.done)
+	}
+}
+
+func (c *Conn) readLoop() {
+	for {
+		data, err := c.codec.Read()
+		if err != nil {
+			c.shutdown(fmt.Errorf("jsonrpc: %w", err))
+			return
+		}
+		select {
+		case <-c.done:
+			return
+		default:
+		}
+
+		var msg message
+		if err := json.Unmarshal(data, &msg); err != nil {
+			_ = c.write(&message{JSONRPC: "2.0", ID: json.RawMessage("null"), Error: &Error{Code: CodeParseError, Message: err.Error()}})
+			continue
+		}
+
+		if msg.Method == "" {
+			c.mu.Lock()
+			reply := c.pending[string(msg.ID)]
+			c.mu.Unlock()
+			if reply != nil {
+				reply <- &msg
+			}
+			continue
+		}
+		c.handlers.Add(1)
+		go func() {
+			defer c.handlers.Done()
+			c.handle(&msg)
+		}()
+	}
+}
+
+// handle serves one incoming request or notification.
+func (c *Conn) handle(msg *message) {
+	var result any
+	err := MethodNotFound(msg.Method)
+	if c.handler != nil {
+		result, err = c.handler(context.Background(), msg.Method, msg.Params)
+	}
+	if len(msg.ID) == 0 {
+		return
+	}
+
+	response := &message{JSONRPC: "2.0", ID: msg.ID}
+	if err != nil {
+		var rpcErr *Error
+		if !errors.As(err, &rpcErr) {
+			rpcErr = &Error{Code: CodeInternalError, Message: err.Error()}
+		}
+		response.Error = rpcErr
+	} else {
+		data, err := json.Marshal(result)
+		if err != nil {
+			response.Error = &Error{Code: CodeInternalError, Message: err.Error()}
+		} else {
+			response.Result = data
+		}
+	}
+	_ = c.write(response)
+}
@@ -1,52 +1,238 @@
 package logger
 
 import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
 	"fmt"
-	"log"
+	"io"
+	"log/slog"
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
 	"time"
+
+	"agent/redact"
+)
+
+// Event types recorded in the session log.
+const (
+	EventUserMessage   = "user_message"
+	EventModelRequest  = "model_request"
+	EventModelResponse = "model_response"
+	EventToolCall      = "tool_call"
+	EventToolResult    = "tool_result"
+	EventError         = "error"
 )
 
+// Options configures where session logs are written and how long they are kept.
+type Options struct {
+	Dir string
+	// MaxFileSize and MaxFileAge start a new part of the current session's
+	// log once the current part exceeds them. Zero means no limit.
+	MaxFileSize int64
+	MaxFileAge  time.Duration
+	// MaxAge and MaxSessions bound how many old sessions are kept; older
+	// ones are deleted when a session starts. Zero means no limit.
+	MaxAge      time.Duration
+	MaxSessions int
+}
+
 var (
-	logFile *os.File
-	logger  *log.Logger
-	mu      sync.Mutex
+	logFile   *rotatingFile
+	logger    = slog.New(slog.NewJSONHandler(io.Discard, nil))
+	level     = new(slog.LevelVar)
+	sessionID string
+	logDir    string
+	turn      atomic.Int64
+	mu        sync.Mutex
+	redactor  atomic.Pointer[redact.Redactor]
 )
 
-// Initialize sets up the logger with the specified log file path
-func Initialize(logDir string) error {
+func init() {
+	redactor.Store(redact.Default())
+}
+
+// SetRedactor sets the Redactor that masks secrets in every string written
+// to the log. The built-in detectors are used until it is called.
+func SetRedactor(r *redact.Redactor) {
+	redactor.Store(r)
+}
+
+// contentAttrs are the attributes holding conversation and tool content,
+// which may contain secrets. IDs, names and other structural attributes are
+// written unchanged, so that logs can be replayed and exported.
+var contentAttrs = map[string]bool{
+	"text":    true,
+	"input":   true,
+	"result":  true,
+	"error":   true,
+	"message": true,
+}
+
+// redactAttr masks secrets in string and raw JSON content attributes.
+func redactAttr(groups []string, a slog.Attr) slog.Attr {
+	if len(groups) > 0 || !contentAttrs[a.Key] {
+		return a
+	}
+	r := redactor.Load()
+	switch a.Value.Kind() {
+	case slog.KindString:
+		a.Value = slog.StringValue(r.Redact(a.Value.String()))
+	case slog.KindAny:
+		if raw, ok := a.Value.Any().(json.RawMessage); ok {
+			redacted := []byte(r.Redact(string(raw)))
+			if !json.Valid(redacted) {
+				redacted, _ = json.Marshal(string(redacted))
+			}
+			a.Value = slog.AnyValue(json.RawMessage(redacted))
+		}
+	}
+	return a
+}
+
+// Initialize starts a new session and writes its events as JSON lines to a
+// file in opts.Dir named after the session, after deleting old sessions
+// beyond the retention limits.
+func Initialize(opts Options) error {
 	mu.Lock()
 	defer mu.Unlock()
 
-	// Create logs directory if it doesn't exist
-	if err := os.MkdirAll(logDir, 0755); err != nil {
+	// Create logs directory if it doesn't exist. Logs hold the conversation,
+	// so only the user may read them.
+	if err := os.MkdirAll(opts.Dir, 0700); err != nil {
 		return fmt.Errorf("failed to create log directory: %v", err)
 	}
 
-	// Create a new log file with timestamp
-	timestamp := time.Now().Format("2006-01-02_15-04-05")
-	logPath := filepath.Join(logDir, fmt.Sprintf("chat_%s.log", timestamp))
-	
-	file, err := os.OpenFile(logPath, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0644)
+	sessionID = newSessionID()
+	logDir = opts.Dir
+	logPath := filepath.Join(opts.Dir, fmt.Sprintf("session_%s.jsonl", sessionID))
+	file, err := openRotatingFile(logPath, opts.MaxFileSize, opts.MaxFileAge)
 	if err != nil {
-		return fmt.Errorf("failed to open log file: %v", err)
+		return err
 	}
+	// The new session counts towards MaxSessions but is never pruned.
+	_, pruneErr := Prune(opts.Dir, opts.MaxAge, opts.MaxSessions, sessionID)
 
 	logFile = file
-	logger = log.New(file, "", log.LstdFlags)
+	handler := slog.NewJSONHandler(file, &slog.HandlerOptions{Level: level, ReplaceAttr: redactAttr})
+	logger = slog.New(handler).With("session_id", sessionID)
+	if pruneErr != nil {
+		logger.Warn("failed to prune old logs", "event", EventError, "error", pruneErr.Error())
+	}
 	return nil
 }
 
-// LogMessage logs a chat message with its source (User/AI)
-func LogMessage(source, content string) {
+// newSessionID returns a sortable, unique session identifier such as
+// 20250102-150405-1a2b3c.
+func newSessionID() string {
+	suffix := make([]byte, 3)
+	_, _ = rand.Read(suffix)
+	return time.Now().Format("20060102-150405") + "-" + hex.EncodeToString(suffix)
+}
+
+// SessionID returns the identifier of the current session.
+func SessionID() string {
 	mu.Lock()
 	defer mu.Unlock()
+	return sessionID
+}
+
+// Dir returns the directory the session log is written to.
+func Dir() string {
+	mu.Lock()
+	defer mu.Unlock()
+	return logDir
+}
+
+// SetLevel sets the minimum level of events written, given as debug, info,
+// warn or error.
+func SetLevel(name string) error {
+	var l slog.Level
+	if err := l.UnmarshalText([]byte(name)); err != nil {
+		return fmt.Errorf("invalid log level %q", name)
+	}
+	level.Set(l)
+	return nil
+}
+
+// Slog returns the session logger, for packages that want to record their
+// own events. Records carry the session ID.
+func Slog() *slog.Logger {
+	mu.Lock()
+	defer mu.Unlock()
+	return logger
+}
+
+// log writes an event of the given type with the current turn.
+func log(l slog.Level, event, msg string, attrs ...any) {
+	attrs = append([]any{"event", event, "turn", turn.Load()}, attrs...)
+	Slog().Log(context.Background(), l, msg, attrs...)
+}
+
+// UserMessage records a message from the user, which starts a new turn.
+func UserMessage(text string) {
+	turn.Add(1)
+	log(slog.LevelInfo, EventUserMessage, "user message", "text", text)
+}
+
+// ModelRequest records a request to the model, with a digest of its
+// messages that lets a replay check it sends the same requests.
+func ModelRequest(model string, messages, tools int, digest string) {
+	log(slog.LevelInfo, EventModelRequest, "model request", "model", model, "messages", messages, "tools", tools, "messages_sha256", digest)
+}
+
+// ModelResponse records a reply from the model: its text, the names of the
+// tools it called, why it stopped, the tokens used and the whole message as
+// returned by the API, from which a replay reproduces it.
+func ModelResponse(text string, toolCalls []string, stopReason string, inputTokens, outputTokens int64, message json.RawMessage) {
+	attrs := []any{
+		"text", text,
+		"tool_calls", toolCalls,
+		"stop_reason", stopReason,
+		slog.Group("usage", "input_tokens", inputTokens, "output_tokens", outputTokens),
+	}
+	if len(message) > 0 {
+		attrs = append(attrs, "message", json.RawMessage(compact(message)))
+	}
+	log(slog.LevelInfo, EventModelResponse, "model response", attrs...)
+}
+
+// ToolCall records the start of a tool call.
+func ToolCall(id, name string, input json.RawMessage) {
+	log(slog.LevelInfo, EventToolCall, "tool call", "tool_use_id", id, "tool", name, "input", json.RawMessage(compact(input)))
+}
+
+// ToolResult records the outcome of a tool call.
+func ToolResult(id, name, result string, err error, duration time.Duration) {
+	attrs := []any{"tool_use_id", id, "tool", name, "duration_ms", duration.Milliseconds()}
+	if err != nil {
+		log(slog.LevelWarn, EventToolResult, "tool failed", append(attrs, "error", err.Error())...)
+		return
+	}
+	log(slog.LevelInfo, EventToolResult, "tool result", append(attrs, "result", result)...)
+}
+
+// Error records a failure, with optional attributes as key-value pairs.
+func Error(msg string, err error, attrs ...any) {
+	if err != nil {
+		attrs = append([]any{"error", err.Error()}, attrs...)
+	}
+	log(slog.LevelError, EventError, msg, attrs...)
+}
 
-	if logger != nil {
-		logger.Printf("[%s] %s", source, content)
+// compact returns input as compact JSON, or as a JSON string if it is not
+// valid JSON.
+func compact(input json.RawMessage) []byte {
+	var out bytes.Buffer
+	if err := json.Compact(&out, input); err != nil || out.Len() == 0 {
+		quoted, _ := json.Marshal(string(input))
+		return quoted
 	}
+	return out.Bytes()
 }
 
 // Close closes the log file
@@ -54,6 +240,7 @@ func Close() error {
 	mu.Lock()
 	defer mu.Unlock()
 
+	logger = slog.New(slog.NewJSONHandler(io.Discard, nil))
 	if logFile != nil {
 		return logFile.Close()
 	}
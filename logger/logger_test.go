@@ -0,0 +1,115 @@
+package logger
+
+import (
+	"bufio"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// readEvents returns the events of the current session's log.
+func readEvents(t *testing.T, dir string) []map[string]any {
+	t.Helper()
+	session, err := FindSession(dir, "latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var events []map[string]any
+	for _, path := range session.Files {
+		file, err := os.Open(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		scanner := bufio.NewScanner(file)
+		for scanner.Scan() {
+			var event map[string]any
+			if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+				t.Fatalf("invalid log line %s: %v", scanner.Text(), err)
+			}
+			events = append(events, event)
+		}
+		file.Close()
+	}
+	return events
+}
+
+func TestRedactsContentOnly(t *testing.T) {
+	dir := t.TempDir()
+	if err := Initialize(Options{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	defer Close()
+
+	const secret = "sk-ant-REDACTED"
+	ids := []string{"toolu_01Xk9pQz7RvW3mNb8Lc2Ht5Y", "toolu_01Qm4Tz8Kd2Wn6Rb9Xc3Lp7V"}
+	ToolCall(ids[0], "read_file", json.RawMessage(`{"path": "`+secret+`"}`))
+	ToolResult(ids[0], "read_file", "API_KEY="+secret, nil, time.Millisecond)
+	ToolCall(ids[1], "read_file", json.RawMessage(`{"path": "go.sum"}`))
+	ToolResult(ids[1], "read_file", "", errors.New("cannot read "+secret), time.Millisecond)
+	// Attributes other than content are written as they are, however random.
+	const checkpoint = "Zq8vN2rT5xK1mW7pL4sJ9bH3cF6gD0aY"
+	Error("failed to restore checkpoint", nil, "checkpoint", checkpoint)
+	Close()
+
+	events := readEvents(t, dir)
+	if len(events) != 5 {
+		t.Fatalf("logged %d events, want 5", len(events))
+	}
+	if got := events[4]["checkpoint"]; got != checkpoint {
+		t.Errorf("checkpoint = %v, want %s", got, checkpoint)
+	}
+	for i, event := range events[:4] {
+		if got, want := event["tool_use_id"], ids[i/2]; got != want {
+			t.Errorf("event %d: tool_use_id = %v, want %s", i, got, want)
+		}
+		if event["session_id"] != SessionID() {
+			t.Errorf("event %d: session_id = %v, want %s", i, event["session_id"], SessionID())
+		}
+		line, _ := json.Marshal(event)
+		if strings.Contains(string(line), secret) {
+			t.Errorf("event %d holds the secret: %s", i, line)
+		}
+	}
+	if input, _ := json.Marshal(events[0]["input"]); string(input) != `{"path":"[REDACTED:anthropic-key]"}` {
+		t.Errorf("input = %s", input)
+	}
+	if got := events[1]["result"]; got != "API_KEY=[REDACTED:anthropic-key]" {
+		t.Errorf("result = %v", got)
+	}
+	if got := events[3]["error"]; got != "cannot read [REDACTED:anthropic-key]" {
+		t.Errorf("error = %v", got)
+	}
+}
+
+func TestLogPermissions(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "logs")
+	if err := Initialize(Options{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	UserMessage("hello")
+	Close()
+
+	checkMode(t, dir, 0700)
+	session, err := FindSession(dir, "latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, path := range session.Files {
+		checkMode(t, path, 0600)
+	}
+}
+
+func checkMode(t *testing.T, path string, want os.FileMode) {
+	t.Helper()
+	info, err := os.Stat(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := info.Mode().Perm(); got != want {
+		t.Errorf("%s has mode %v, want %v", path, got, want)
+	}
+}
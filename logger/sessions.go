@@ -0,0 +1,152 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// sessionFileRe matches session log files: the current part
+// session_<id>.jsonl and rotated parts session_<id>.<n>.jsonl.
+var sessionFileRe = regexp.MustCompile(`^session_([^.]+)(?:\.(\d+))?\.jsonl$`)
+
+// Session describes the log files of one session.
+type Session struct {
+	ID      string
+	Files   []string // in the order they were written
+	Size    int64
+	Started time.Time
+	Updated time.Time
+}
+
+// ListSessions returns the sessions logged in dir, oldest first.
+func ListSessions(dir string) ([]Session, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	type part struct {
+		path string
+		n    int // 0 for the current part, which was written last
+	}
+	parts := make(map[string][]part)
+	sessions := make(map[string]*Session)
+	for _, entry := range entries {
+		m := sessionFileRe.FindStringSubmatch(entry.Name())
+		if m == nil || entry.IsDir() {
+			continue
+		}
+		info, err := entry.Info()
+		if err != nil {
+			continue
+		}
+		id := m[1]
+		s := sessions[id]
+		if s == nil {
+			s = &Session{ID: id, Started: info.ModTime()}
+			if started, err := time.ParseInLocation("20060102-150405", id[:min(len(id), 15)], time.Local); err == nil {
+				s.Started = started
+			}
+			sessions[id] = s
+		}
+		s.Size += info.Size()
+		if info.ModTime().After(s.Updated) {
+			s.Updated = info.ModTime()
+		}
+		n, _ := strconv.Atoi(m[2])
+		parts[id] = append(parts[id], part{path: filepath.Join(dir, entry.Name()), n: n})
+	}
+
+	result := make([]Session, 0, len(sessions))
+	for id, s := range sessions {
+		ps := parts[id]
+		sort.Slice(ps, func(i, j int) bool {
+			if (ps[i].n == 0) != (ps[j].n == 0) {
+				return ps[j].n == 0
+			}
+			return ps[i].n < ps[j].n
+		})
+		for _, p := range ps {
+			s.Files = append(s.Files, p.path)
+		}
+		result = append(result, *s)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].ID < result[j].ID })
+	return result, nil
+}
+
+// FindSession returns the session in dir whose ID starts with id, or the
+// most recent session if id is empty or "latest".
+func FindSession(dir, id string) (Session, error) {
+	sessions, err := ListSessions(dir)
+	if err != nil {
+		return Session{}, err
+	}
+	if len(sessions) == 0 {
+		return Session{}, fmt.Errorf("no sessions found in %s", dir)
+	}
+	if id == "" || id == "latest" {
+		return sessions[len(sessions)-1], nil
+	}
+	var matches []Session
+	for _, s := range sessions {
+		if s.ID == id {
+			return s, nil
+		}
+		if strings.HasPrefix(s.ID, id) {
+			matches = append(matches, s)
+		}
+	}
+	switch len(matches) {
+	case 0:
+		return Session{}, fmt.Errorf("no session %q in %s", id, dir)
+	case 1:
+		return matches[0], nil
+	}
+	return Session{}, fmt.Errorf("%q matches %d sessions; give more of the ID", id, len(matches))
+}
+
+// Prune deletes the sessions in dir last written more than maxAge ago, and
+// then the oldest sessions beyond the newest maxSessions. A zero limit is
+// not applied. The session with ID keep, normally the current one, is never
+// deleted. It returns the deleted sessions.
+func Prune(dir string, maxAge time.Duration, maxSessions int, keep string) ([]Session, error) {
+	sessions, err := ListSessions(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var removed []Session
+	var errs []string
+	remaining := len(sessions)
+	for _, s := range sessions {
+		if s.ID == keep {
+			continue
+		}
+		tooOld := maxAge > 0 && time.Since(s.Updated) > maxAge
+		tooMany := maxSessions > 0 && remaining > maxSessions
+		if !tooOld && !tooMany {
+			continue
+		}
+		for _, path := range s.Files {
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				errs = append(errs, err.Error())
+			}
+		}
+		removed = append(removed, s)
+		remaining--
+	}
+	if len(errs) > 0 {
+		return removed, fmt.Errorf("failed to remove some log files: %s", strings.Join(errs, "; "))
+	}
+	return removed, nil
+}
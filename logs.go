@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"agent/config"
+	"agent/logger"
+)
+
+const logsUsage = `usage: agent logs <command>
+
+commands:
+  list                          list logged sessions
+  tail [session] [-n N] [-f]    print the last lines of a session's log (default the latest)
+  prune [-max-age D] [-keep N]  delete old sessions`
+
+// logsCommand lists, tails and prunes the session logs.
+func logsCommand(cfg *config.Config, args []string) error {
+	if len(args) == 0 {
+		return fmt.Errorf("%s", logsUsage)
+	}
+	dir := cfg.Logs.LogDir()
+	switch args[0] {
+	case "list":
+		return listLogs(dir)
+	case "tail":
+		return tailLogs(dir, args[1:])
+	case "prune":
+		return pruneLogs(cfg, dir, args[1:])
+	}
+	return fmt.Errorf("unknown logs command %q\n%s", args[0], logsUsage)
+}
+
+func listLogs(dir string) error {
+	sessions, err := logger.ListSessions(dir)
+	if err != nil {
+		return err
+	}
+	if len(sessions) == 0 {
+		fmt.Printf("No sessions logged in %s\n", dir)
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SESSION\tSTARTED\tUPDATED\tSIZE\tFILES")
+	for _, s := range sessions {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%d\n", s.ID,
+			s.Started.Format(time.DateTime), s.Updated.Format(time.DateTime),
+			formatSize(s.Size), len(s.Files))
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Printf("\n%d sessions in %s\n", len(sessions), dir)
+	return nil
+}
+
+func tailLogs(dir string, args []string) error {
+	// Accept the session before or after the flags.
+	var id string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		id, args = args[0], args[1:]
+	}
+	flags := flag.NewFlagSet("logs tail", flag.ExitOnError)
+	lines := flags.Int("n", 20, "number of lines to print")
+	follow := flags.Bool("f", false, "keep printing lines as they are written")
+	flags.Parse(args)
+	if id == "" {
+		id = flags.Arg(0)
+	}
+
+	session, err := logger.FindSession(dir, id)
+	if err != nil {
+		return err
+	}
+
+	// Read the parts newest first until enough lines are found.
+	var last []string
+	for i := len(session.Files) - 1; i >= 0 && len(last) < *lines; i-- {
+		data, err := os.ReadFile(session.Files[i])
+		if err != nil {
+			return err
+		}
+		partLines := strings.Split(strings.TrimSuffix(string(data), "\n"), "\n")
+		if len(data) == 0 {
+			partLines = nil
+		}
+		last = append(partLines, last...)
+	}
+	if len(last) > *lines {
+		last = last[len(last)-*lines:]
+	}
+	for _, line := range last {
+		fmt.Println(line)
+	}
+
+	if *follow {
+		return followLog(session.Files[len(session.Files)-1])
+	}
+	return nil
+}
+
+// followLog prints lines appended to the current part of a session's log,
+// starting again from the beginning when the part is rotated.
+func followLog(path string) error {
+	var offset int64
+	if info, err := os.Stat(path); err == nil {
+		offset = info.Size()
+	}
+	var partial []byte
+	for {
+		time.Sleep(500 * time.Millisecond)
+		info, err := os.Stat(path)
+		if err != nil {
+			continue
+		}
+		if info.Size() < offset {
+			offset, partial = 0, nil
+		}
+		if info.Size() == offset {
+			continue
+		}
+		file, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		_, err = file.Seek(offset, io.SeekStart)
+		if err == nil {
+			var data []byte
+			data, err = io.ReadAll(bufio.NewReader(file))
+			offset += int64(len(data))
+			partial = append(partial, data...)
+			if i := bytes.LastIndexByte(partial, '\n'); i >= 0 {
+				os.Stdout.Write(partial[:i+1])
+				partial = append([]byte(nil), partial[i+1:]...)
+			}
+		}
+		file.Close()
+		if err != nil {
+			return err
+		}
+	}
+}
+
+func pruneLogs(cfg *config.Config, dir string, args []string) error {
+	_, _, maxAge, maxSessions := cfg.Logs.Limits()
+	flags := flag.NewFlagSet("logs prune", flag.ExitOnError)
+	flags.DurationVar(&maxAge, "max-age", maxAge, "delete sessions last written longer ago than this (0 for no limit)")
+	flags.IntVar(&maxSessions, "keep", maxSessions, "keep at most this many sessions (0 for no limit)")
+	flags.Parse(args)
+
+	removed, err := logger.Prune(dir, maxAge, maxSessions, "")
+	for _, s := range removed {
+		fmt.Printf("Deleted session %s (%s)\n", s.ID, formatSize(s.Size))
+	}
+	if err != nil {
+		return err
+	}
+	if len(removed) == 0 {
+		fmt.Println("No sessions to delete")
+	}
+	return nil
+}
+
+// formatSize formats a byte count for display.
+func formatSize(n int64) string {
+	switch {
+	case n >= 1<<20:
+		return fmt.Sprintf("%.1f MB", float64(n)/(1<<20))
+	case n >= 1<<10:
+		return fmt.Sprintf("%.1f KB", float64(n)/(1<<10))
+	}
+	return fmt.Sprintf("%d B", n)
+}
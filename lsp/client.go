@@ -0,0 +1,271 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"sync"
+	"time"
+
+	"agent/jsonrpc"
+)
+
+// diagnosticsTimeout bounds how long to wait for a server to publish
+// diagnostics after a document changes.
+const diagnosticsTimeout = 5 * time.Second
+
+// Client is a connection to a single language server.
+type Client struct {
+	conn   *jsonrpc.Conn
+	closer io.Closer
+	cmd    *exec.Cmd
+
+	// syncMu serializes Sync, so that a document is opened before it is
+	// changed and its versions reach the server in order.
+	syncMu sync.Mutex
+
+	mu          sync.Mutex
+	versions    map[string]int          // open documents by URI
+	diagnostics map[string][]Diagnostic // latest diagnostics by URI
+	published   map[string]int          // number of publishes by URI
+	updated     chan struct{}           // closed and replaced on every publish
+}
+
+// NewClient speaks LSP over rwc, which is closed by Close. It does not
+// initialize the session; call Initialize before any other method.
+func NewClient(rwc io.ReadWriteCloser) *Client {
+	c := &Client{
+		closer:      rwc,
+		versions:    make(map[string]int),
+		diagnostics: make(map[string][]Diagnostic),
+		published:   make(map[string]int),
+		updated:     make(chan struct{}),
+	}
+	c.conn = jsonrpc.NewConn(jsonrpc.NewHeaderCodec(rwc, rwc), c.handle)
+	return c
+}
+
+// stdio joins a process's stdout and stdin into a single stream.
+type stdio struct {
+	io.ReadCloser
+	stdin io.WriteCloser
+}
+
+func (s stdio) Write(p []byte) (int, error) { return s.stdin.Write(p) }
+
+func (s stdio) Close() error {
+	s.stdin.Close()
+	return s.ReadCloser.Close()
+}
+
+// Start spawns a language server process and initializes it for root.
+func Start(ctx context.Context, command []string, root string, initOptions json.RawMessage) (*Client, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("language server command is empty")
+	}
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Dir = root
+	cmd.Stderr = io.Discard
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", command[0], err)
+	}
+
+	c := NewClient(stdio{ReadCloser: stdout, stdin: stdin})
+	c.cmd = cmd
+	if err := c.Initialize(ctx, root, initOptions); err != nil {
+		c.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// handle serves notifications and requests sent by the server.
+func (c *Client) handle(ctx context.Context, method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "textDocument/publishDiagnostics":
+		var p publishDiagnosticsParams
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		c.mu.Lock()
+		c.diagnostics[p.URI] = p.Diagnostics
+		c.published[p.URI]++
+		close(c.updated)
+		c.updated = make(chan struct{})
+		c.mu.Unlock()
+		return nil, nil
+	case "workspace/configuration":
+		var p struct {
+			Items []json.RawMessage `json:"items"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, err
+		}
+		return make([]any, len(p.Items)), nil
+	case "client/registerCapability", "client/unregisterCapability",
+		"window/workDoneProgress/create", "window/showMessageRequest":
+		return nil, nil
+	case "window/logMessage", "window/showMessage", "$/progress", "telemetry/event":
+		return nil, nil
+	}
+	return nil, jsonrpc.MethodNotFound(method)
+}
+
+// Initialize performs the initialize handshake for a workspace rooted at root.
+func (c *Client) Initialize(ctx context.Context, root string, initOptions json.RawMessage) error {
+	params := map[string]any{
+		"processId": os.Getpid(),
+		"rootUri":   PathToURI(root),
+		"workspaceFolders": []map[string]string{
+			{"uri": PathToURI(root), "name": root},
+		},
+		"capabilities": map[string]any{
+			"textDocument": map[string]any{
+				"synchronization":    map[string]any{"didSave": true},
+				"publishDiagnostics": map[string]any{"versionSupport": true},
+				"hover":              map[string]any{"contentFormat": []string{"markdown", "plaintext"}},
+				"definition":         map[string]any{"linkSupport": false},
+			},
+			"workspace": map[string]any{"configuration": true, "workspaceFolders": true},
+		},
+	}
+	if len(initOptions) > 0 {
+		params["initializationOptions"] = initOptions
+	}
+	if err := c.conn.Call(ctx, "initialize", params, nil); err != nil {
+		return fmt.Errorf("language server initialize failed: %w", err)
+	}
+	return c.conn.Notify("initialized", struct{}{})
+}
+
+// Sync opens the document at path with the given content, or sends the new
+// content if it is already open. It returns the number of diagnostics
+// publishes seen for the document before the change, for WaitDiagnostics.
+func (c *Client) Sync(path, languageID, content string) (int, error) {
+	uri := PathToURI(path)
+	c.syncMu.Lock()
+	defer c.syncMu.Unlock()
+
+	c.mu.Lock()
+	version, open := c.versions[uri]
+	version++
+	c.versions[uri] = version
+	seen := c.published[uri]
+	c.mu.Unlock()
+
+	if !open {
+		return seen, c.conn.Notify("textDocument/didOpen", map[string]any{
+			"textDocument": textDocumentItem{URI: uri, LanguageID: languageID, Version: version, Text: content},
+		})
+	}
+	return seen, c.conn.Notify("textDocument/didChange", map[string]any{
+		"textDocument":   versionedTextDocumentIdentifier{URI: uri, Version: version},
+		"contentChanges": []textDocumentContentChangeEvent{{Text: content}},
+	})
+}
+
+// WaitDiagnostics waits until the server publishes diagnostics for path more
+// than seen times, or the timeout elapses, and returns the latest diagnostics.
+func (c *Client) WaitDiagnostics(ctx context.Context, path string, seen int) []Diagnostic {
+	uri := PathToURI(path)
+	timeout := time.NewTimer(diagnosticsTimeout)
+	defer timeout.Stop()
+
+	for {
+		c.mu.Lock()
+		if c.published[uri] > seen {
+			diagnostics := c.diagnostics[uri]
+			c.mu.Unlock()
+			return diagnostics
+		}
+		updated := c.updated
+		c.mu.Unlock()
+
+		select {
+		case <-updated:
+		case <-timeout.C:
+			return c.Diagnostics(path)
+		case <-ctx.Done():
+			return c.Diagnostics(path)
+		case <-c.conn.Done():
+			return c.Diagnostics(path)
+		}
+	}
+}
+
+// Diagnostics returns the most recently published diagnostics for path.
+func (c *Client) Diagnostics(path string) []Diagnostic {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.diagnostics[PathToURI(path)]
+}
+
+// Hover returns the hover text at pos in path.
+func (c *Client) Hover(ctx context.Context, path string, pos Position) (string, error) {
+	var result *hoverResult
+	params := textDocumentPositionParams{TextDocument: textDocumentIdentifier{URI: PathToURI(path)}, Position: pos}
+	if err := c.conn.Call(ctx, "textDocument/hover", params, &result); err != nil {
+		return "", err
+	}
+	if result == nil {
+		return "", nil
+	}
+	return result.text(), nil
+}
+
+// Definition returns the locations where the symbol at pos in path is defined.
+func (c *Client) Definition(ctx context.Context, path string, pos Position) ([]Location, error) {
+	var raw json.RawMessage
+	params := textDocumentPositionParams{TextDocument: textDocumentIdentifier{URI: PathToURI(path)}, Position: pos}
+	if err := c.conn.Call(ctx, "textDocument/definition", params, &raw); err != nil {
+		return nil, err
+	}
+
+	// The result may be a single Location, an array of them, or null.
+	var locations []Location
+	if err := json.Unmarshal(raw, &locations); err == nil {
+		return locations, nil
+	}
+	var location Location
+	if err := json.Unmarshal(raw, &location); err == nil && location.URI != "" {
+		return []Location{location}, nil
+	}
+	return nil, nil
+}
+
+// Close shuts the server down, waiting briefly for it to exit.
+func (c *Client) Close() error {
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := c.conn.Call(ctx, "shutdown", nil, nil); err == nil {
+		_ = c.conn.Notify("exit", nil)
+	}
+	c.conn.Close()
+	err := c.closer.Close()
+
+	if c.cmd != nil {
+		exited := make(chan struct{})
+		go func() {
+			c.cmd.Wait()
+			close(exited)
+		}()
+		select {
+		case <-exited:
+		case <-time.After(2 * time.Second):
+			c.cmd.Process.Kill()
+			<-exited
+		}
+	}
+	return err
+}
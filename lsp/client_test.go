@@ -0,0 +1,189 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+
+	"agent/jsonrpc"
+)
+
+// serverMessage is a request or notification received by fakeServer.
+type serverMessage struct {
+	ID     json.RawMessage `json:"id,omitempty"`
+	Method string          `json:"method"`
+	Params json.RawMessage `json:"params,omitempty"`
+}
+
+// fakeServer is the server end of a net.Pipe. It answers requests with the
+// canned results for their method and queues every message it receives, in
+// order, for the test to check.
+type fakeServer struct {
+	codec    jsonrpc.Codec
+	results  map[string]any
+	received chan serverMessage
+}
+
+func newFakeServer(t *testing.T, results map[string]any) (*fakeServer, *Client) {
+	t.Helper()
+	clientEnd, serverEnd := net.Pipe()
+	s := &fakeServer{
+		codec:    jsonrpc.NewHeaderCodec(serverEnd, serverEnd),
+		results:  results,
+		received: make(chan serverMessage, 100),
+	}
+	go func() {
+		defer close(s.received)
+		for {
+			data, err := s.codec.Read()
+			if err != nil {
+				return
+			}
+			var msg serverMessage
+			if err := json.Unmarshal(data, &msg); err != nil {
+				t.Errorf("server received invalid message: %v", err)
+				return
+			}
+			s.received <- msg
+			if len(msg.ID) > 0 {
+				s.send(map[string]any{"jsonrpc": "2.0", "id": msg.ID, "result": s.results[msg.Method]})
+			}
+		}
+	}()
+	t.Cleanup(func() { serverEnd.Close() })
+	return s, NewClient(clientEnd)
+}
+
+func (s *fakeServer) send(msg map[string]any) {
+	data, _ := json.Marshal(msg)
+	s.codec.Write(data)
+}
+
+// notify sends a notification to the client.
+func (s *fakeServer) notify(method string, params any) {
+	s.send(map[string]any{"jsonrpc": "2.0", "method": method, "params": params})
+}
+
+// expect returns the next message the server received, failing unless it is
+// for method.
+func (s *fakeServer) expect(t *testing.T, method string) serverMessage {
+	t.Helper()
+	select {
+	case msg, ok := <-s.received:
+		if !ok {
+			t.Fatalf("connection closed while waiting for %s", method)
+		}
+		if msg.Method != method {
+			t.Fatalf("server received %s, want %s", msg.Method, method)
+		}
+		return msg
+	case <-time.After(5 * time.Second):
+		t.Fatalf("timed out waiting for %s", method)
+	}
+	return serverMessage{}
+}
+
+func TestClient(t *testing.T) {
+	ctx := context.Background()
+	root := t.TempDir()
+	path := filepath.Join(root, "main.go")
+	uri := PathToURI(path)
+
+	target := Location{URI: PathToURI(filepath.Join(root, "lib.go")), Range: Range{Start: Position{Line: 4, Character: 5}, End: Position{Line: 4, Character: 8}}}
+	server, client := newFakeServer(t, map[string]any{
+		"initialize": map[string]any{"capabilities": map[string]any{}},
+		// A single location rather than an array, which servers may send.
+		"textDocument/definition": target,
+	})
+
+	if err := client.Initialize(ctx, root, json.RawMessage(`{"verbose":true}`)); err != nil {
+		t.Fatalf("Initialize: %v", err)
+	}
+	var initParams struct {
+		RootURI               string          `json:"rootUri"`
+		InitializationOptions json.RawMessage `json:"initializationOptions"`
+	}
+	json.Unmarshal(server.expect(t, "initialize").Params, &initParams)
+	if initParams.RootURI != PathToURI(root) {
+		t.Errorf("rootUri = %q, want %q", initParams.RootURI, PathToURI(root))
+	}
+	if string(initParams.InitializationOptions) != `{"verbose":true}` {
+		t.Errorf("initializationOptions = %s", initParams.InitializationOptions)
+	}
+	server.expect(t, "initialized")
+
+	seen, err := client.Sync(path, "go", "package main\n")
+	if err != nil {
+		t.Fatalf("Sync: %v", err)
+	}
+	var openParams struct {
+		TextDocument textDocumentItem `json:"textDocument"`
+	}
+	json.Unmarshal(server.expect(t, "textDocument/didOpen").Params, &openParams)
+	want := textDocumentItem{URI: uri, LanguageID: "go", Version: 1, Text: "package main\n"}
+	if openParams.TextDocument != want {
+		t.Errorf("didOpen document = %+v, want %+v", openParams.TextDocument, want)
+	}
+
+	server.notify("textDocument/publishDiagnostics", publishDiagnosticsParams{
+		URI:         uri,
+		Diagnostics: []Diagnostic{{Severity: SeverityError, Message: "undefined: x"}},
+	})
+	diagnostics := client.WaitDiagnostics(ctx, path, seen)
+	if len(diagnostics) != 1 || diagnostics[0].Message != "undefined: x" {
+		t.Errorf("diagnostics = %+v", diagnostics)
+	}
+
+	locations, err := client.Definition(ctx, path, Position{Line: 2, Character: 1})
+	if err != nil {
+		t.Fatalf("Definition: %v", err)
+	}
+	if len(locations) != 1 || locations[0] != target {
+		t.Errorf("locations = %+v, want [%+v]", locations, target)
+	}
+	var positionParams textDocumentPositionParams
+	json.Unmarshal(server.expect(t, "textDocument/definition").Params, &positionParams)
+	if positionParams.TextDocument.URI != uri || positionParams.Position != (Position{Line: 2, Character: 1}) {
+		t.Errorf("definition params = %+v", positionParams)
+	}
+
+	if err := client.Close(); err != nil {
+		t.Errorf("Close: %v", err)
+	}
+	server.expect(t, "shutdown")
+	server.expect(t, "exit")
+}
+
+func TestClientSyncOrder(t *testing.T) {
+	server, client := newFakeServer(t, map[string]any{"shutdown": nil})
+	defer client.Close()
+	path := filepath.Join(t.TempDir(), "main.go")
+
+	const syncs = 20
+	var wg sync.WaitGroup
+	for range syncs {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if _, err := client.Sync(path, "go", "package main\n"); err != nil {
+				t.Errorf("Sync: %v", err)
+			}
+		}()
+	}
+	wg.Wait()
+
+	server.expect(t, "textDocument/didOpen")
+	for version := 2; version <= syncs; version++ {
+		var params struct {
+			TextDocument versionedTextDocumentIdentifier `json:"textDocument"`
+		}
+		json.Unmarshal(server.expect(t, "textDocument/didChange").Params, &params)
+		if params.TextDocument.Version != version {
+			t.Fatalf("didChange version = %d, want %d", params.TextDocument.Version, version)
+		}
+	}
+}
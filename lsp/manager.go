@@ -0,0 +1,207 @@
+package lsp
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"unicode/utf16"
+
+	"agent/config"
+)
+
+// Manager starts the configured language servers on demand and routes files
+// to them by extension.
+type Manager struct {
+	root    string
+	servers []config.LanguageServer
+
+	mu      sync.Mutex
+	clients map[string]*Client // by server name
+}
+
+// NewManager returns a manager for the workspace at root. No servers are
+// started until a file they handle is used.
+func NewManager(root string, servers []config.LanguageServer) *Manager {
+	return &Manager{
+		root:    root,
+		servers: servers,
+		clients: make(map[string]*Client),
+	}
+}
+
+// serverFor returns the configured server handling path, if any.
+func (m *Manager) serverFor(path string) (config.LanguageServer, bool) {
+	ext := filepath.Ext(path)
+	for _, server := range m.servers {
+		for _, e := range server.Extensions {
+			if strings.EqualFold(e, ext) {
+				return server, true
+			}
+		}
+	}
+	return config.LanguageServer{}, false
+}
+
+// Handles reports whether a language server is configured for path.
+func (m *Manager) Handles(path string) bool {
+	_, ok := m.serverFor(path)
+	return ok
+}
+
+// clientFor returns a running client for the server handling path, starting it if needed.
+func (m *Manager) clientFor(ctx context.Context, path string) (*Client, config.LanguageServer, error) {
+	server, ok := m.serverFor(path)
+	if !ok {
+		return nil, server, fmt.Errorf("no language server configured for %s files", filepath.Ext(path))
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if client, ok := m.clients[server.Name]; ok {
+		select {
+		case <-client.conn.Done():
+			// The server died; start a new one below.
+			delete(m.clients, server.Name)
+		default:
+			return client, server, nil
+		}
+	}
+
+	client, err := Start(ctx, server.Command, m.root, server.InitializationOptions)
+	if err != nil {
+		return nil, server, fmt.Errorf("language server %s: %w", server.Name, err)
+	}
+	m.clients[server.Name] = client
+	return client, server, nil
+}
+
+// sync sends the current on-disk content of path to its server.
+func (m *Manager) sync(ctx context.Context, path string) (*Client, int, error) {
+	client, server, err := m.clientFor(ctx, path)
+	if err != nil {
+		return nil, 0, err
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	languageID := server.LanguageID
+	if languageID == "" {
+		languageID = languageIDs[strings.ToLower(filepath.Ext(path))]
+	}
+	seen, err := client.Sync(path, languageID, string(content))
+	return client, seen, err
+}
+
+// Diagnostics sends the current content of path to its server and waits for
+// the resulting diagnostics.
+func (m *Manager) Diagnostics(ctx context.Context, path string) ([]Diagnostic, error) {
+	client, seen, err := m.sync(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	return client.WaitDiagnostics(ctx, path, seen), nil
+}
+
+// Hover returns hover information at a 1-based line and column of path.
+func (m *Manager) Hover(ctx context.Context, path string, line, column int) (string, error) {
+	client, _, err := m.sync(ctx, path)
+	if err != nil {
+		return "", err
+	}
+	pos, err := toPosition(path, line, column)
+	if err != nil {
+		return "", err
+	}
+	return client.Hover(ctx, path, pos)
+}
+
+// Definition returns the definition locations for a 1-based line and column of path.
+func (m *Manager) Definition(ctx context.Context, path string, line, column int) ([]Location, error) {
+	client, _, err := m.sync(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	pos, err := toPosition(path, line, column)
+	if err != nil {
+		return nil, err
+	}
+	return client.Definition(ctx, path, pos)
+}
+
+// Close shuts down every running server.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name, client := range m.clients {
+		client.Close()
+		delete(m.clients, name)
+	}
+}
+
+// toPosition converts a 1-based line and rune column to an LSP position,
+// whose character offset counts UTF-16 code units.
+func toPosition(path string, line, column int) (Position, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return Position{}, err
+	}
+	lines := strings.Split(string(content), "\n")
+	if line < 1 || line > len(lines) {
+		return Position{}, fmt.Errorf("line %d is out of range; %s has %d lines", line, path, len(lines))
+	}
+	runes := []rune(lines[line-1])
+	if column < 1 {
+		column = 1
+	}
+	if column-1 > len(runes) {
+		column = len(runes) + 1
+	}
+	return Position{Line: line - 1, Character: len(utf16.Encode(runes[:column-1]))}, nil
+}
+
+// FormatDiagnostics renders diagnostics as path:line:col: severity: message lines.
+func FormatDiagnostics(path string, diagnostics []Diagnostic) string {
+	var out strings.Builder
+	for _, d := range diagnostics {
+		fmt.Fprintf(&out, "%s:%d:%d: %s: %s", path, d.Range.Start.Line+1, d.Range.Start.Character+1, d.Severity, d.Message)
+		if d.Source != "" {
+			fmt.Fprintf(&out, " (%s)", d.Source)
+		}
+		out.WriteString("\n")
+	}
+	return out.String()
+}
+
+// PostEditFeedback collects errors and warnings for the changed files that a
+// language server handles, for appending to the result of the tool that
+// changed them.
+func (m *Manager) PostEditFeedback(ctx context.Context, changed []string) string {
+	var feedback strings.Builder
+	for _, path := range changed {
+		if !m.Handles(path) {
+			continue
+		}
+		if _, err := os.Stat(path); err != nil {
+			continue
+		}
+		diagnostics, err := m.Diagnostics(ctx, path)
+		if err != nil {
+			continue
+		}
+		var problems []Diagnostic
+		for _, d := range diagnostics {
+			if d.Severity == 0 || d.Severity <= SeverityWarning {
+				problems = append(problems, d)
+			}
+		}
+		feedback.WriteString(FormatDiagnostics(path, problems))
+	}
+	if feedback.Len() == 0 {
+		return ""
+	}
+	return "\n\nLanguage server diagnostics after this change:\n" + feedback.String()
+}
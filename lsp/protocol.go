@@ -0,0 +1,156 @@
+package lsp
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+)
+
+// Position is a zero-based line and UTF-16 character offset.
+type Position struct {
+	Line      int `json:"line"`
+	Character int `json:"character"`
+}
+
+// Range is a span between two positions in a document.
+type Range struct {
+	Start Position `json:"start"`
+	End   Position `json:"end"`
+}
+
+// Location is a range in a document identified by URI.
+type Location struct {
+	URI   string `json:"uri"`
+	Range Range  `json:"range"`
+}
+
+// DiagnosticSeverity ranks diagnostics; lower is more severe.
+type DiagnosticSeverity int
+
+const (
+	SeverityError       DiagnosticSeverity = 1
+	SeverityWarning     DiagnosticSeverity = 2
+	SeverityInformation DiagnosticSeverity = 3
+	SeverityHint        DiagnosticSeverity = 4
+)
+
+func (s DiagnosticSeverity) String() string {
+	switch s {
+	case SeverityError:
+		return "error"
+	case SeverityWarning:
+		return "warning"
+	case SeverityInformation:
+		return "info"
+	case SeverityHint:
+		return "hint"
+	}
+	return "diagnostic"
+}
+
+// Diagnostic is a problem reported by a language server.
+type Diagnostic struct {
+	Range    Range              `json:"range"`
+	Severity DiagnosticSeverity `json:"severity,omitempty"`
+	Code     any                `json:"code,omitempty"`
+	Source   string             `json:"source,omitempty"`
+	Message  string             `json:"message"`
+}
+
+type publishDiagnosticsParams struct {
+	URI         string       `json:"uri"`
+	Version     *int         `json:"version,omitempty"`
+	Diagnostics []Diagnostic `json:"diagnostics"`
+}
+
+type textDocumentIdentifier struct {
+	URI string `json:"uri"`
+}
+
+type textDocumentItem struct {
+	URI        string `json:"uri"`
+	LanguageID string `json:"languageId"`
+	Version    int    `json:"version"`
+	Text       string `json:"text"`
+}
+
+type versionedTextDocumentIdentifier struct {
+	URI     string `json:"uri"`
+	Version int    `json:"version"`
+}
+
+type textDocumentContentChangeEvent struct {
+	Text string `json:"text"`
+}
+
+type textDocumentPositionParams struct {
+	TextDocument textDocumentIdentifier `json:"textDocument"`
+	Position     Position               `json:"position"`
+}
+
+// hoverResult holds the contents field of a hover response, which may be
+// MarkupContent, a MarkedString or an array of MarkedStrings.
+type hoverResult struct {
+	Contents json.RawMessage `json:"contents"`
+}
+
+// text flattens hover contents into plain text.
+func (h hoverResult) text() string {
+	var markup struct {
+		Value string `json:"value"`
+	}
+	var plain string
+	var list []json.RawMessage
+	switch {
+	case json.Unmarshal(h.Contents, &plain) == nil:
+		return plain
+	case json.Unmarshal(h.Contents, &list) == nil:
+		var parts []string
+		for _, item := range list {
+			parts = append(parts, hoverResult{Contents: item}.text())
+		}
+		return strings.Join(parts, "\n\n")
+	case json.Unmarshal(h.Contents, &markup) == nil:
+		return markup.Value
+	}
+	return string(h.Contents)
+}
+
+// languageIDs maps file extensions to LSP language identifiers.
+var languageIDs = map[string]string{
+	".go":   "go",
+	".py":   "python",
+	".ts":   "typescript",
+	".tsx":  "typescriptreact",
+	".js":   "javascript",
+	".jsx":  "javascriptreact",
+	".rs":   "rust",
+	".c":    "c",
+	".h":    "c",
+	".cpp":  "cpp",
+	".java": "java",
+	".json": "json",
+}
+
+// PathToURI converts a file path to a file:// URI.
+func PathToURI(path string) string {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(abs)}).String()
+}
+
+// URIToPath converts a file:// URI back to a file path.
+func URIToPath(uri string) (string, error) {
+	u, err := url.Parse(uri)
+	if err != nil {
+		return "", err
+	}
+	if u.Scheme != "file" {
+		return "", fmt.Errorf("unsupported URI scheme %q", u.Scheme)
+	}
+	return filepath.FromSlash(u.Path), nil
+}
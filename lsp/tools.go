@@ -0,0 +1,145 @@
+package lsp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"agent/tools"
+)
+
+type DiagnosticsInput struct {
+	Path string `json:"path" jsonschema_description:"The relative path of the file to check."`
+}
+
+type PositionInput struct {
+	Path   string `json:"path" jsonschema_description:"The relative path of the file containing the symbol."`
+	Line   int    `json:"line" jsonschema:"minimum=1" jsonschema_description:"The 1-based line number on which the symbol appears."`
+	Symbol string `json:"symbol" jsonschema_description:"The identifier to look up, exactly as written on that line."`
+	Column int    `json:"column,omitempty" jsonschema:"minimum=1" jsonschema_description:"Optional 1-based column of the symbol, only needed if it appears more than once on the line."`
+}
+
+// column resolves the 1-based column of the symbol named in input.
+func (input PositionInput) column() (int, error) {
+	if input.Path == "" || input.Line <= 0 || input.Symbol == "" {
+		return 0, fmt.Errorf("invalid input parameters")
+	}
+	if input.Column > 0 {
+		return input.Column, nil
+	}
+	content, err := os.ReadFile(input.Path)
+	if err != nil {
+		return 0, err
+	}
+	lines := strings.Split(string(content), "\n")
+	if input.Line > len(lines) {
+		return 0, fmt.Errorf("line %d is out of range; %s has %d lines", input.Line, input.Path, len(lines))
+	}
+	idx := strings.Index(lines[input.Line-1], input.Symbol)
+	if idx < 0 {
+		return 0, fmt.Errorf("symbol %q not found on line %d of %s", input.Symbol, input.Line, input.Path)
+	}
+	return len([]rune(lines[input.Line-1][:idx])) + 1, nil
+}
+
+// ToolDefinitions returns the language server tools backed by m.
+func (m *Manager) ToolDefinitions() []tools.ToolDefinition {
+	return []tools.ToolDefinition{
+		{
+			Name:        "lsp_diagnostics",
+			Description: "Get errors and warnings for a file from its language server (e.g. TypeScript or Python type errors). Returns one 'path:line:column: severity: message' line per problem.",
+			InputSchema: tools.GenerateSchema[DiagnosticsInput](),
+			Function:    m.diagnosticsTool,
+			ReadOnly:    true,
+		},
+		{
+			Name:        "lsp_hover",
+			Description: "Get the language server's hover information (type, signature and documentation) for a symbol. Give the file, the line and the symbol as written on that line.",
+			InputSchema: tools.GenerateSchema[PositionInput](),
+			Function:    m.hoverTool,
+			ReadOnly:    true,
+		},
+		{
+			Name:        "lsp_definition",
+			Description: "Find where a symbol is defined using its language server. Give the file, the line and the symbol as written on that line. Returns file:line:column locations.",
+			InputSchema: tools.GenerateSchema[PositionInput](),
+			Function:    m.definitionTool,
+			ReadOnly:    true,
+		},
+	}
+}
+
+func (m *Manager) diagnosticsTool(input json.RawMessage) (string, error) {
+	diagnosticsInput := DiagnosticsInput{}
+	err := json.Unmarshal(input, &diagnosticsInput)
+	if err != nil {
+		return "", err
+	}
+	if diagnosticsInput.Path == "" {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+
+	diagnostics, err := m.Diagnostics(context.Background(), diagnosticsInput.Path)
+	if err != nil {
+		return "", err
+	}
+	if len(diagnostics) == 0 {
+		return fmt.Sprintf("No problems reported for %s", diagnosticsInput.Path), nil
+	}
+	return FormatDiagnostics(diagnosticsInput.Path, diagnostics), nil
+}
+
+func (m *Manager) hoverTool(input json.RawMessage) (string, error) {
+	positionInput := PositionInput{}
+	err := json.Unmarshal(input, &positionInput)
+	if err != nil {
+		return "", err
+	}
+	column, err := positionInput.column()
+	if err != nil {
+		return "", err
+	}
+
+	text, err := m.Hover(context.Background(), positionInput.Path, positionInput.Line, column)
+	if err != nil {
+		return "", err
+	}
+	if text == "" {
+		return fmt.Sprintf("No hover information for %s", positionInput.Symbol), nil
+	}
+	return text, nil
+}
+
+func (m *Manager) definitionTool(input json.RawMessage) (string, error) {
+	positionInput := PositionInput{}
+	err := json.Unmarshal(input, &positionInput)
+	if err != nil {
+		return "", err
+	}
+	column, err := positionInput.column()
+	if err != nil {
+		return "", err
+	}
+
+	locations, err := m.Definition(context.Background(), positionInput.Path, positionInput.Line, column)
+	if err != nil {
+		return "", err
+	}
+	if len(locations) == 0 {
+		return fmt.Sprintf("No definition found for %s", positionInput.Symbol), nil
+	}
+	var result strings.Builder
+	for _, loc := range locations {
+		path, err := URIToPath(loc.URI)
+		if err != nil {
+			path = loc.URI
+		} else if rel, err := filepath.Rel(m.root, path); err == nil && !strings.HasPrefix(rel, "..") {
+			path = rel
+		}
+		fmt.Fprintf(&result, "%s:%d:%d\n", path, loc.Range.Start.Line+1, loc.Range.Start.Character+1)
+	}
+	return result.String(), nil
+}
@@ -1,10 +1,11 @@
 package main
 
 import (
+	"fmt"
 	"log"
-	"path/filepath"
+	"os"
 
-	"agent/agent"
+	"agent/config"
 	"agent/logger"
 	"agent/models"
 	"agent/tools"
@@ -13,32 +14,87 @@ import (
 )
 
 func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	for _, note := range cfg.Ignored {
+		fmt.Fprintln(os.Stderr, "warning: "+note)
+	}
+
+	if len(os.Args) > 1 && os.Args[1] == "logs" {
+		if err := logsCommand(cfg, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "export" {
+		if err := exportCommand(cfg, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+
 	// Initialize logger
-	logDir := filepath.Join(".", "logs")
-	if err := logger.Initialize(logDir); err != nil {
+	maxFileSize, maxFileAge, maxAge, maxSessions := cfg.Logs.Limits()
+	err = logger.Initialize(logger.Options{
+		Dir:         cfg.Logs.LogDir(),
+		MaxFileSize: maxFileSize,
+		MaxFileAge:  maxFileAge,
+		MaxAge:      maxAge,
+		MaxSessions: maxSessions,
+	})
+	if err != nil {
 		log.Fatal("Failed to initialize logger:", err)
 	}
 	defer logger.Close()
+	logger.SetRedactor(redactor(cfg))
+	if cfg.LogLevel != "" {
+		if err := logger.SetLevel(cfg.LogLevel); err != nil {
+			log.Fatal(err)
+		}
+	}
+	defer setupTracing(cfg)()
 
-	client := anthropic.NewClient()
-	toolDefs := []tools.ToolDefinition{
-		tools.ReadFileDefinition,
-		tools.EditFileDefinition,
-		tools.ListFilesDefinition,
+	if len(os.Args) > 1 && os.Args[1] == "mcp-serve" {
+		if err := mcpServe(cfg, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "eval" {
+		if err := evalCommand(cfg, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
+	}
+	if len(os.Args) > 1 && os.Args[1] == "replay" {
+		if err := replayCommand(cfg, os.Args[2:]); err != nil {
+			log.Fatal(err)
+		}
+		return
 	}
-	myAgent := agent.NewAgent(&client, nil, toolDefs)
+
+	client := anthropic.NewClient()
+	env := setup(cfg, &client, true)
+	defer env.Close()
 
 	m := &models.MainModel{
-		Agent: myAgent,
+		Agent:         env.agent,
+		MCP:           env.mcp,
+		MaxInputLines: cfg.UI.InputLines(),
+		HistoryFile:   config.HistoryFile(tools.WorkspaceRoot()),
+		MarkdownStyle: markdownStyle(cfg),
+		Notices:       cfg.Ignored,
 	}
-	
+
 	// Create a program with the full terminal option
 	p := tea.NewProgram(
 		m,
 		tea.WithAltScreen(),       // Use alternate screen buffer
 		tea.WithMouseCellMotion(), // Enable mouse support
 	)
-	
+
 	if err := p.Start(); err != nil {
 		log.Fatal(err)
 	}
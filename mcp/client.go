@@ -0,0 +1,276 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"os/exec"
+	"time"
+
+	"agent/jsonrpc"
+)
+
+// clientInfo identifies this agent to servers.
+var clientInfo = Implementation{Name: "code-editing-agent", Version: "0.1.0"}
+
+// transport carries JSON-RPC calls to a server.
+type transport interface {
+	Call(ctx context.Context, method string, params, result any) error
+	Notify(method string, params any) error
+	Close() error
+}
+
+// Client is an initialized session with a single MCP server.
+type Client struct {
+	transport    transport
+	cmd          *exec.Cmd
+	serverInfo   Implementation
+	capabilities map[string]json.RawMessage
+	instructions string
+}
+
+// stdio joins a process's stdout and stdin into a single stream.
+type stdio struct {
+	io.ReadCloser
+	stdin io.WriteCloser
+}
+
+func (s stdio) Write(p []byte) (int, error) { return s.stdin.Write(p) }
+
+func (s stdio) Close() error {
+	s.stdin.Close()
+	return s.ReadCloser.Close()
+}
+
+// stdioTransport is a jsonrpc.Conn that also closes the underlying stream.
+type stdioTransport struct {
+	*jsonrpc.Conn
+	rwc io.ReadWriteCloser
+}
+
+func (t stdioTransport) Close() error {
+	t.Conn.Close()
+	return t.rwc.Close()
+}
+
+// NewClient speaks MCP over rwc using newline-delimited JSON, and performs
+// the initialize handshake. root is reported to servers that ask for roots.
+func NewClient(ctx context.Context, rwc io.ReadWriteCloser, root string) (*Client, error) {
+	conn := jsonrpc.NewConn(jsonrpc.NewLineCodec(rwc, rwc), serverRequestHandler(root))
+	c := &Client{transport: stdioTransport{Conn: conn, rwc: rwc}}
+	if err := c.initialize(ctx); err != nil {
+		c.transport.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// Start spawns an MCP server process and initializes a session with it.
+func Start(ctx context.Context, command []string, env map[string]string, root string) (*Client, error) {
+	if len(command) == 0 {
+		return nil, fmt.Errorf("server command is empty")
+	}
+	cmd := exec.Command(command[0], command[1:]...)
+	cmd.Dir = root
+	cmd.Env = os.Environ()
+	for key, value := range env {
+		cmd.Env = append(cmd.Env, key+"="+value)
+	}
+	cmd.Stderr = io.Discard
+	stdin, err := cmd.StdinPipe()
+	if err != nil {
+		return nil, err
+	}
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := cmd.Start(); err != nil {
+		return nil, fmt.Errorf("failed to start %s: %w", command[0], err)
+	}
+
+	c, err := NewClient(ctx, stdio{ReadCloser: stdout, stdin: stdin}, root)
+	if err != nil {
+		cmd.Process.Kill()
+		cmd.Wait()
+		return nil, err
+	}
+	c.cmd = cmd
+	return c, nil
+}
+
+// Connect initializes a session with a server using the streamable HTTP
+// transport. headers are sent with every request, e.g. for authorization.
+func Connect(ctx context.Context, endpoint string, headers map[string]string) (*Client, error) {
+	c := &Client{transport: newHTTPTransport(endpoint, headers)}
+	if err := c.initialize(ctx); err != nil {
+		c.transport.Close()
+		return nil, err
+	}
+	return c, nil
+}
+
+// serverRequestHandler answers the requests a server may send to the client.
+func serverRequestHandler(root string) jsonrpc.Handler {
+	return func(ctx context.Context, method string, params json.RawMessage) (any, error) {
+		switch method {
+		case "ping":
+			return struct{}{}, nil
+		case "roots/list":
+			return map[string]any{
+				"roots": []map[string]string{{"uri": (&url.URL{Scheme: "file", Path: root}).String(), "name": root}},
+			}, nil
+		case "notifications/message", "notifications/progress", "notifications/cancelled",
+			"notifications/tools/list_changed", "notifications/resources/list_changed",
+			"notifications/prompts/list_changed", "notifications/resources/updated":
+			return nil, nil
+		}
+		return nil, jsonrpc.MethodNotFound(method)
+	}
+}
+
+// initialize performs the initialize handshake.
+func (c *Client) initialize(ctx context.Context) error {
+	params := map[string]any{
+		"protocolVersion": protocolVersion,
+		"capabilities":    map[string]any{"roots": map[string]any{}},
+		"clientInfo":      clientInfo,
+	}
+	var result initializeResult
+	if err := c.transport.Call(ctx, "initialize", params, &result); err != nil {
+		return fmt.Errorf("initialize failed: %w", err)
+	}
+	c.serverInfo = result.ServerInfo
+	c.capabilities = result.Capabilities
+	c.instructions = result.Instructions
+	return c.transport.Notify("notifications/initialized", nil)
+}
+
+// ServerInfo returns the name and version the server reported.
+func (c *Client) ServerInfo() Implementation {
+	return c.serverInfo
+}
+
+// Instructions returns the usage instructions the server sent, if any.
+func (c *Client) Instructions() string {
+	return c.instructions
+}
+
+// HasCapability reports whether the server advertised a capability such as
+// "tools", "resources" or "prompts".
+func (c *Client) HasCapability(name string) bool {
+	_, ok := c.capabilities[name]
+	return ok
+}
+
+// cursorParams returns the params for a paginated list request.
+func cursorParams(cursor string) map[string]any {
+	if cursor == "" {
+		return map[string]any{}
+	}
+	return map[string]any{"cursor": cursor}
+}
+
+// ListTools returns every tool the server offers.
+func (c *Client) ListTools(ctx context.Context) ([]Tool, error) {
+	var tools []Tool
+	cursor := ""
+	for {
+		var result listToolsResult
+		if err := c.transport.Call(ctx, "tools/list", cursorParams(cursor), &result); err != nil {
+			return nil, err
+		}
+		tools = append(tools, result.Tools...)
+		if result.NextCursor == "" {
+			return tools, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// CallTool invokes a tool. A tool that fails reports it through the
+// result's IsError field rather than the returned error.
+func (c *Client) CallTool(ctx context.Context, name string, arguments json.RawMessage) (CallToolResult, error) {
+	if len(arguments) == 0 {
+		arguments = json.RawMessage("{}")
+	}
+	var result CallToolResult
+	err := c.transport.Call(ctx, "tools/call", map[string]any{"name": name, "arguments": arguments}, &result)
+	return result, err
+}
+
+// ListResources returns every resource the server offers.
+func (c *Client) ListResources(ctx context.Context) ([]Resource, error) {
+	var resources []Resource
+	cursor := ""
+	for {
+		var result listResourcesResult
+		if err := c.transport.Call(ctx, "resources/list", cursorParams(cursor), &result); err != nil {
+			return nil, err
+		}
+		resources = append(resources, result.Resources...)
+		if result.NextCursor == "" {
+			return resources, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// ReadResource returns the contents of the resource at uri.
+func (c *Client) ReadResource(ctx context.Context, uri string) ([]ResourceContents, error) {
+	var result readResourceResult
+	if err := c.transport.Call(ctx, "resources/read", map[string]any{"uri": uri}, &result); err != nil {
+		return nil, err
+	}
+	return result.Contents, nil
+}
+
+// ListPrompts returns every prompt the server offers.
+func (c *Client) ListPrompts(ctx context.Context) ([]Prompt, error) {
+	var prompts []Prompt
+	cursor := ""
+	for {
+		var result listPromptsResult
+		if err := c.transport.Call(ctx, "prompts/list", cursorParams(cursor), &result); err != nil {
+			return nil, err
+		}
+		prompts = append(prompts, result.Prompts...)
+		if result.NextCursor == "" {
+			return prompts, nil
+		}
+		cursor = result.NextCursor
+	}
+}
+
+// GetPrompt expands a prompt template with the given arguments.
+func (c *Client) GetPrompt(ctx context.Context, name string, arguments map[string]string) (GetPromptResult, error) {
+	params := map[string]any{"name": name}
+	if len(arguments) > 0 {
+		params["arguments"] = arguments
+	}
+	var result GetPromptResult
+	err := c.transport.Call(ctx, "prompts/get", params, &result)
+	return result, err
+}
+
+// Close ends the session, waiting briefly for a server process to exit.
+func (c *Client) Close() error {
+	err := c.transport.Close()
+	if c.cmd != nil {
+		exited := make(chan struct{})
+		go func() {
+			c.cmd.Wait()
+			close(exited)
+		}()
+		select {
+		case <-exited:
+		case <-time.After(2 * time.Second):
+			c.cmd.Process.Kill()
+			<-exited
+		}
+	}
+	return err
+}
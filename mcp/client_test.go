@@ -0,0 +1,160 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"time"
+
+	"agent/jsonrpc"
+	"agent/tools"
+)
+
+// pipeEnd is one end of a pair of io.Pipes.
+type pipeEnd struct {
+	*io.PipeReader
+	*io.PipeWriter
+}
+
+func (p pipeEnd) Close() error {
+	p.PipeWriter.Close()
+	return p.PipeReader.Close()
+}
+
+type echoInput struct {
+	Text string `json:"text" jsonschema_description:"The text to echo."`
+}
+
+// serve runs a Server offering defs over io.Pipes and returns a client
+// connected to it. The server must have stopped by the end of the test.
+func serve(t *testing.T, defs []tools.ToolDefinition) *Client {
+	t.Helper()
+	clientRead, serverWrite := io.Pipe()
+	serverRead, clientWrite := io.Pipe()
+	server := NewServer(Implementation{Name: "test-server", Version: "1.2.3"}, "Use echo to echo.", defs, nil)
+
+	served := make(chan error, 1)
+	go func() {
+		served <- server.Serve(context.Background(), serverRead, serverWrite)
+		serverWrite.Close()
+	}()
+
+	client, err := NewClient(context.Background(), pipeEnd{clientRead, clientWrite}, t.TempDir())
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	t.Cleanup(func() {
+		client.Close()
+		select {
+		case err := <-served:
+			if err != nil {
+				t.Errorf("Serve: %v", err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Error("server did not stop after the client closed")
+		}
+	})
+	return client
+}
+
+func TestClientServer(t *testing.T) {
+	ctx := context.Background()
+	echo := tools.ToolDefinition{
+		Name:        "echo",
+		Description: "Echo the text back.",
+		InputSchema: tools.GenerateSchema[echoInput](),
+		Function: func(input json.RawMessage) (string, error) {
+			var in echoInput
+			if err := json.Unmarshal(input, &in); err != nil {
+				return "", err
+			}
+			return in.Text, nil
+		},
+		ReadOnly: true,
+	}
+	fail := tools.ToolDefinition{
+		Name:        "fail",
+		Description: "Always fail.",
+		InputSchema: tools.GenerateSchema[struct{}](),
+		Function: func(input json.RawMessage) (string, error) {
+			return "", errors.New("it failed")
+		},
+	}
+	client := serve(t, []tools.ToolDefinition{echo, fail})
+
+	if info := client.ServerInfo(); info != (Implementation{Name: "test-server", Version: "1.2.3"}) {
+		t.Errorf("ServerInfo = %+v", info)
+	}
+	if got := client.Instructions(); got != "Use echo to echo." {
+		t.Errorf("Instructions = %q", got)
+	}
+	if !client.HasCapability("tools") || client.HasCapability("resources") {
+		t.Errorf("capabilities = %v, want only tools", client.capabilities)
+	}
+
+	list, err := client.ListTools(ctx)
+	if err != nil {
+		t.Fatalf("ListTools: %v", err)
+	}
+	if len(list) != 2 || list[0].Name != "echo" || list[1].Name != "fail" {
+		t.Fatalf("ListTools = %+v, want echo and fail", list)
+	}
+	if list[0].Description != echo.Description {
+		t.Errorf("description = %q, want %q", list[0].Description, echo.Description)
+	}
+	if list[0].Annotations == nil || !list[0].Annotations.ReadOnlyHint {
+		t.Errorf("echo annotations = %+v, want read-only", list[0].Annotations)
+	}
+	if list[1].Annotations != nil {
+		t.Errorf("fail annotations = %+v, want none", list[1].Annotations)
+	}
+	// The schema survives the round trip.
+	schema, err := tools.SchemaFromJSON(list[0].InputSchema)
+	if err != nil {
+		t.Fatalf("SchemaFromJSON: %v", err)
+	}
+	got, _ := tools.SchemaJSON(schema)
+	want, _ := tools.SchemaJSON(echo.InputSchema)
+	if !jsonEqual(t, got, want) {
+		t.Errorf("echo schema = %s, want %s", got, want)
+	}
+
+	result, err := client.CallTool(ctx, "echo", json.RawMessage(`{"text":"hello"}`))
+	if err != nil {
+		t.Fatalf("CallTool echo: %v", err)
+	}
+	if result.IsError || result.Text() != "hello" {
+		t.Errorf("echo result = %+v, want hello", result)
+	}
+
+	result, err = client.CallTool(ctx, "fail", nil)
+	if err != nil {
+		t.Fatalf("CallTool fail: %v", err)
+	}
+	if !result.IsError || result.Text() != "it failed" {
+		t.Errorf("fail result = %+v, want the error reported in the result", result)
+	}
+
+	_, err = client.CallTool(ctx, "missing", nil)
+	var rpcErr *jsonrpc.Error
+	if !errors.As(err, &rpcErr) || rpcErr.Code != jsonrpc.CodeInvalidParams || !strings.Contains(rpcErr.Message, "missing") {
+		t.Errorf("CallTool missing: err = %v, want an invalid params error", err)
+	}
+}
+
+func jsonEqual(t *testing.T, a, b json.RawMessage) bool {
+	t.Helper()
+	var x, y any
+	if err := json.Unmarshal(a, &x); err != nil {
+		t.Fatal(err)
+	}
+	if err := json.Unmarshal(b, &y); err != nil {
+		t.Fatal(err)
+	}
+	ja, _ := json.Marshal(x)
+	jb, _ := json.Marshal(y)
+	return string(ja) == string(jb)
+}
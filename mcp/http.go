@@ -0,0 +1,204 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"agent/jsonrpc"
+)
+
+// sessionHeader carries the session ID assigned by a streamable HTTP server.
+const sessionHeader = "Mcp-Session-Id"
+
+// httpMessage is a JSON-RPC message sent or received over HTTP.
+type httpMessage struct {
+	JSONRPC string          `json:"jsonrpc"`
+	ID      *int64          `json:"id,omitempty"`
+	Method  string          `json:"method,omitempty"`
+	Params  any             `json:"params,omitempty"`
+	Result  json.RawMessage `json:"result,omitempty"`
+	Error   *jsonrpc.Error  `json:"error,omitempty"`
+}
+
+// httpTransport implements the MCP streamable HTTP transport: every message
+// is POSTed to a single endpoint, which answers with either a JSON body or an
+// event stream carrying the response.
+type httpTransport struct {
+	endpoint string
+	headers  map[string]string
+	client   *http.Client
+	nextID   atomic.Int64
+
+	mu        sync.Mutex
+	sessionID string
+}
+
+func newHTTPTransport(endpoint string, headers map[string]string) *httpTransport {
+	return &httpTransport{endpoint: endpoint, headers: headers, client: &http.Client{}}
+}
+
+// post sends msg and returns the response, which the caller must close.
+func (t *httpTransport) post(ctx context.Context, msg httpMessage) (*http.Response, error) {
+	body, err := json.Marshal(msg)
+	if err != nil {
+		return nil, err
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, t.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Accept", "application/json, text/event-stream")
+	t.setHeaders(req)
+
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	if id := resp.Header.Get(sessionHeader); id != "" {
+		t.mu.Lock()
+		t.sessionID = id
+		t.mu.Unlock()
+	}
+	if resp.StatusCode >= 300 {
+		defer resp.Body.Close()
+		text, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return nil, fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(text)))
+	}
+	return resp, nil
+}
+
+func (t *httpTransport) setHeaders(req *http.Request) {
+	for key, value := range t.headers {
+		req.Header.Set(key, value)
+	}
+	t.mu.Lock()
+	if t.sessionID != "" {
+		req.Header.Set(sessionHeader, t.sessionID)
+	}
+	t.mu.Unlock()
+}
+
+func (t *httpTransport) Call(ctx context.Context, method string, params, result any) error {
+	id := t.nextID.Add(1)
+	resp, err := t.post(ctx, httpMessage{JSONRPC: "2.0", ID: &id, Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	var reply *httpMessage
+	mediaType, _, _ := mime.ParseMediaType(resp.Header.Get("Content-Type"))
+	if mediaType == "text/event-stream" {
+		reply, err = readEventStream(resp.Body, id)
+	} else {
+		reply, err = readJSONReply(resp.Body, id)
+	}
+	if err != nil {
+		return err
+	}
+	if reply.Error != nil {
+		return reply.Error
+	}
+	if result == nil || len(reply.Result) == 0 {
+		return nil
+	}
+	return json.Unmarshal(reply.Result, result)
+}
+
+// readJSONReply finds the response to id in a JSON body holding a single
+// message or a batch.
+func readJSONReply(body io.Reader, id int64) (*httpMessage, error) {
+	data, err := io.ReadAll(body)
+	if err != nil {
+		return nil, err
+	}
+	var batch []httpMessage
+	if err := json.Unmarshal(data, &batch); err != nil {
+		var msg httpMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			return nil, fmt.Errorf("invalid response: %w", err)
+		}
+		batch = []httpMessage{msg}
+	}
+	for i := range batch {
+		if batch[i].ID != nil && *batch[i].ID == id && batch[i].Method == "" {
+			return &batch[i], nil
+		}
+	}
+	return nil, fmt.Errorf("response did not contain a reply to request %d", id)
+}
+
+// readEventStream reads server-sent events until the response to id arrives.
+// Notifications and requests from the server on the stream are ignored.
+func readEventStream(body io.Reader, id int64) (*httpMessage, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	var data strings.Builder
+	for {
+		more := scanner.Scan()
+		line := scanner.Text()
+		if more && line != "" {
+			if value, ok := strings.CutPrefix(line, "data:"); ok {
+				data.WriteString(strings.TrimPrefix(value, " "))
+				data.WriteString("\n")
+			}
+			continue
+		}
+
+		// A blank line (or the end of the stream) dispatches the event.
+		if data.Len() > 0 {
+			var msg httpMessage
+			if err := json.Unmarshal([]byte(data.String()), &msg); err == nil &&
+				msg.ID != nil && *msg.ID == id && msg.Method == "" {
+				return &msg, nil
+			}
+			data.Reset()
+		}
+		if !more {
+			if err := scanner.Err(); err != nil {
+				return nil, err
+			}
+			return nil, fmt.Errorf("event stream ended without a reply to request %d", id)
+		}
+	}
+}
+
+func (t *httpTransport) Notify(method string, params any) error {
+	resp, err := t.post(context.Background(), httpMessage{JSONRPC: "2.0", Method: method, Params: params})
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
+
+// Close ends the session on the server, if it assigned one.
+func (t *httpTransport) Close() error {
+	t.mu.Lock()
+	sessionID := t.sessionID
+	t.mu.Unlock()
+	if sessionID == "" {
+		return nil
+	}
+	req, err := http.NewRequest(http.MethodDelete, t.endpoint, nil)
+	if err != nil {
+		return err
+	}
+	t.setHeaders(req)
+	resp, err := t.client.Do(req)
+	if err != nil {
+		return err
+	}
+	resp.Body.Close()
+	return nil
+}
@@ -0,0 +1,115 @@
+package mcp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+
+	"agent/config"
+)
+
+// Manager holds the sessions with the configured MCP servers.
+type Manager struct {
+	root    string
+	servers []config.MCPServer
+
+	mu      sync.Mutex
+	clients map[string]*Client // by server name
+	errs    map[string]error   // connection failures by server name
+}
+
+// NewManager returns a manager for the given servers. No connections are made
+// until Connect is called.
+func NewManager(root string, servers []config.MCPServer) *Manager {
+	return &Manager{
+		root:    root,
+		servers: servers,
+		clients: make(map[string]*Client),
+		errs:    make(map[string]error),
+	}
+}
+
+// Connect starts or connects to every enabled server concurrently. Servers
+// that fail are reported in the returned error and skipped; the others
+// remain usable.
+func (m *Manager) Connect(ctx context.Context) error {
+	var wg sync.WaitGroup
+	for _, server := range m.servers {
+		if server.Disabled {
+			continue
+		}
+		wg.Add(1)
+		go func(server config.MCPServer) {
+			defer wg.Done()
+			client, err := connect(ctx, server, m.root)
+
+			m.mu.Lock()
+			defer m.mu.Unlock()
+			if err != nil {
+				m.errs[server.Name] = err
+				return
+			}
+			m.clients[server.Name] = client
+		}(server)
+	}
+	wg.Wait()
+
+	var errs []error
+	for _, name := range m.ServerNames() {
+		if err := m.errs[name]; err != nil {
+			errs = append(errs, fmt.Errorf("mcp server %s: %w", name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// connect opens a session with server using the transport its config selects.
+func connect(ctx context.Context, server config.MCPServer, root string) (*Client, error) {
+	switch {
+	case server.Name == "":
+		return nil, fmt.Errorf("server name is empty")
+	case len(server.Command) > 0 && server.URL != "":
+		return nil, fmt.Errorf("set either command or url, not both")
+	case len(server.Command) > 0:
+		return Start(ctx, server.Command, server.Env, root)
+	case server.URL != "":
+		return Connect(ctx, server.URL, server.Headers)
+	}
+	return nil, fmt.Errorf("either command or url must be set")
+}
+
+// ServerNames returns the names of all configured servers, sorted.
+func (m *Manager) ServerNames() []string {
+	names := make([]string, 0, len(m.servers))
+	for _, server := range m.servers {
+		names = append(names, server.Name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// Client returns the session with the named server, or an error explaining
+// why there is none.
+func (m *Manager) Client(name string) (*Client, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if client, ok := m.clients[name]; ok {
+		return client, nil
+	}
+	if err := m.errs[name]; err != nil {
+		return nil, fmt.Errorf("mcp server %s is not connected: %w", name, err)
+	}
+	return nil, fmt.Errorf("mcp server %s is not connected", name)
+}
+
+// Close ends every session.
+func (m *Manager) Close() {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for name, client := range m.clients {
+		client.Close()
+		delete(m.clients, name)
+	}
+}
@@ -0,0 +1,139 @@
+package mcp
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"agent/jsonrpc"
+	"agent/tools"
+)
+
+// CallFunc runs a tool by name. It lets the server apply the same checks and
+// post-edit feedback as the agent's own tool calls.
+type CallFunc func(ctx context.Context, name string, input json.RawMessage) (string, error)
+
+// Server exposes tool definitions to MCP clients.
+type Server struct {
+	info         Implementation
+	instructions string
+	tools        []tools.ToolDefinition
+	call         CallFunc
+}
+
+// NewServer returns a server offering defs. If call is nil, tools are run by
+// calling their Function directly.
+func NewServer(info Implementation, instructions string, defs []tools.ToolDefinition, call CallFunc) *Server {
+	s := &Server{info: info, instructions: instructions, tools: defs, call: call}
+	if s.call == nil {
+		s.call = s.callDirect
+	}
+	return s
+}
+
+func (s *Server) callDirect(ctx context.Context, name string, input json.RawMessage) (string, error) {
+	for _, def := range s.tools {
+		if def.Name == name {
+			return def.Function(input)
+		}
+	}
+	return "", fmt.Errorf("tool not found: %s", name)
+}
+
+// Serve speaks MCP over newline-delimited JSON on r and w until the client
+// closes the stream or ctx is cancelled.
+func (s *Server) Serve(ctx context.Context, r io.Reader, w io.Writer) error {
+	conn := jsonrpc.NewConn(jsonrpc.NewLineCodec(r, w), s.handle)
+	select {
+	case <-conn.Done():
+		// A client may close its end right after sending its last request;
+		// finish answering before returning.
+		conn.Wait()
+	case <-ctx.Done():
+		conn.Close()
+	}
+	if err := conn.Err(); err != nil && !errors.Is(err, io.EOF) && !errors.Is(err, jsonrpc.ErrClosed) {
+		return err
+	}
+	return nil
+}
+
+func (s *Server) handle(ctx context.Context, method string, params json.RawMessage) (any, error) {
+	switch method {
+	case "initialize":
+		var p struct {
+			ProtocolVersion string `json:"protocolVersion"`
+		}
+		if err := json.Unmarshal(params, &p); err != nil {
+			return nil, &jsonrpc.Error{Code: jsonrpc.CodeInvalidParams, Message: err.Error()}
+		}
+		// Answer with the client's version if it is one we speak, and with
+		// ours otherwise so the client can decide whether to continue.
+		version := protocolVersion
+		if p.ProtocolVersion == "2024-11-05" {
+			version = p.ProtocolVersion
+		}
+		return initializeResult{
+			ProtocolVersion: version,
+			Capabilities:    map[string]json.RawMessage{"tools": json.RawMessage("{}")},
+			ServerInfo:      s.info,
+			Instructions:    s.instructions,
+		}, nil
+	case "ping":
+		return struct{}{}, nil
+	case "tools/list":
+		return s.listTools()
+	case "tools/call":
+		return s.callTool(ctx, params)
+	case "notifications/initialized", "notifications/cancelled", "notifications/roots/list_changed":
+		return nil, nil
+	}
+	return nil, jsonrpc.MethodNotFound(method)
+}
+
+func (s *Server) listTools() (listToolsResult, error) {
+	result := listToolsResult{Tools: make([]Tool, 0, len(s.tools))}
+	for _, def := range s.tools {
+		schema, err := tools.SchemaJSON(def.InputSchema)
+		if err != nil {
+			return result, fmt.Errorf("tool %s: %w", def.Name, err)
+		}
+		tool := Tool{Name: def.Name, Description: def.Description, InputSchema: schema}
+		if def.ReadOnly {
+			tool.Annotations = &ToolAnnotations{ReadOnlyHint: true}
+		}
+		result.Tools = append(result.Tools, tool)
+	}
+	return result, nil
+}
+
+// callTool runs a tool. Failures of the tool itself are reported in the
+// result with IsError set, so the calling model can see and react to them;
+// only malformed requests are protocol errors.
+func (s *Server) callTool(ctx context.Context, params json.RawMessage) (CallToolResult, error) {
+	var p struct {
+		Name      string          `json:"name"`
+		Arguments json.RawMessage `json:"arguments"`
+	}
+	if err := json.Unmarshal(params, &p); err != nil {
+		return CallToolResult{}, &jsonrpc.Error{Code: jsonrpc.CodeInvalidParams, Message: err.Error()}
+	}
+	found := false
+	for _, def := range s.tools {
+		found = found || def.Name == p.Name
+	}
+	if !found {
+		return CallToolResult{}, &jsonrpc.Error{Code: jsonrpc.CodeInvalidParams, Message: "unknown tool: " + p.Name}
+	}
+	if len(p.Arguments) == 0 || string(p.Arguments) == "null" {
+		p.Arguments = json.RawMessage("{}")
+	}
+
+	text, err := s.call(ctx, p.Name, p.Arguments)
+	if err != nil {
+		return CallToolResult{Content: []Content{{Type: "text", Text: err.Error()}}, IsError: true}, nil
+	}
+	return CallToolResult{Content: []Content{{Type: "text", Text: text}}}, nil
+}
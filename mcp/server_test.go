@@ -0,0 +1,146 @@
+package mcp
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"strings"
+	"sync"
+	"testing"
+
+	"agent/jsonrpc"
+	"agent/tools"
+)
+
+// response is a JSON-RPC response written by the server.
+type response struct {
+	ID     json.RawMessage `json:"id"`
+	Result json.RawMessage `json:"result"`
+	Error  *jsonrpc.Error  `json:"error"`
+}
+
+// exchange sends requests to server, one per line, and returns its responses
+// by request ID once the server has answered them all.
+func exchange(t *testing.T, server *Server, requests ...string) map[string]response {
+	t.Helper()
+	var out bytes.Buffer
+	in := strings.NewReader(strings.Join(requests, "\n") + "\n")
+	if err := server.Serve(context.Background(), in, &out); err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+	responses := make(map[string]response)
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var r response
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("invalid response %s: %v", scanner.Text(), err)
+		}
+		responses[string(r.ID)] = r
+	}
+	return responses
+}
+
+// result decodes the successful response with the given ID into v.
+func result(t *testing.T, responses map[string]response, id string, v any) {
+	t.Helper()
+	r, ok := responses[id]
+	if !ok {
+		t.Fatalf("no response to request %s", id)
+	}
+	if r.Error != nil {
+		t.Fatalf("request %s failed: %v", id, r.Error)
+	}
+	if err := json.Unmarshal(r.Result, v); err != nil {
+		t.Fatalf("response to request %s: %v", id, err)
+	}
+}
+
+func TestServer(t *testing.T) {
+	echo := tools.ToolDefinition{
+		Name:        "echo",
+		Description: "Echo the text back.",
+		InputSchema: tools.GenerateSchema[echoInput](),
+		ReadOnly:    true,
+	}
+	fail := tools.ToolDefinition{
+		Name:        "fail",
+		Description: "Always fail.",
+		InputSchema: tools.GenerateSchema[struct{}](),
+	}
+	var mu sync.Mutex
+	inputs := map[string]string{}
+	call := func(ctx context.Context, name string, input json.RawMessage) (string, error) {
+		mu.Lock()
+		inputs[name] = string(input)
+		mu.Unlock()
+		if name == "fail" {
+			return "", errors.New("it failed")
+		}
+		var in echoInput
+		err := json.Unmarshal(input, &in)
+		return in.Text, err
+	}
+	server := NewServer(Implementation{Name: "test-server", Version: "1.2.3"}, "Use echo to echo.", []tools.ToolDefinition{echo, fail}, call)
+
+	responses := exchange(t, server,
+		`{"jsonrpc": "2.0", "id": 1, "method": "initialize", "params": {"protocolVersion": "2024-11-05", "capabilities": {}, "clientInfo": {"name": "test", "version": "1"}}}`,
+		`{"jsonrpc": "2.0", "method": "notifications/initialized"}`,
+		`{"jsonrpc": "2.0", "id": 2, "method": "tools/list"}`,
+		`{"jsonrpc": "2.0", "id": 3, "method": "tools/call", "params": {"name": "echo", "arguments": {"text": "hello"}}}`,
+		`{"jsonrpc": "2.0", "id": 4, "method": "tools/call", "params": {"name": "fail", "arguments": null}}`,
+		`{"jsonrpc": "2.0", "id": 5, "method": "tools/call", "params": {"name": "missing", "arguments": {}}}`,
+		`{"jsonrpc": "2.0", "id": 6, "method": "resources/list"}`,
+	)
+	if len(responses) != 6 {
+		t.Errorf("got %d responses, want 6, none for the notification", len(responses))
+	}
+
+	var init initializeResult
+	result(t, responses, "1", &init)
+	if init.ProtocolVersion != "2024-11-05" || init.ServerInfo.Name != "test-server" || init.Instructions != "Use echo to echo." {
+		t.Errorf("initialize = %+v", init)
+	}
+	if _, ok := init.Capabilities["tools"]; !ok || len(init.Capabilities) != 1 {
+		t.Errorf("capabilities = %v, want only tools", init.Capabilities)
+	}
+
+	var list listToolsResult
+	result(t, responses, "2", &list)
+	if len(list.Tools) != 2 || list.Tools[0].Name != "echo" || list.Tools[1].Name != "fail" {
+		t.Fatalf("tools/list = %+v, want echo and fail", list)
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(list.Tools[0].InputSchema, &schema); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := schema["-"]; ok || schema["type"] != "object" {
+		t.Errorf("echo schema = %s", list.Tools[0].InputSchema)
+	}
+
+	var echoed CallToolResult
+	result(t, responses, "3", &echoed)
+	if echoed.IsError || echoed.Text() != "hello" {
+		t.Errorf("echo result = %+v, want hello", echoed)
+	}
+
+	var failed CallToolResult
+	result(t, responses, "4", &failed)
+	if !failed.IsError || failed.Text() != "it failed" {
+		t.Errorf("fail result = %+v, want the error reported in the result", failed)
+	}
+	if inputs["fail"] != "{}" {
+		t.Errorf("null arguments were passed as %q, want {}", inputs["fail"])
+	}
+
+	if r := responses["5"]; r.Error == nil || r.Error.Code != jsonrpc.CodeInvalidParams || !strings.Contains(r.Error.Message, "missing") {
+		t.Errorf("unknown tool: error = %v, want an invalid params error", r.Error)
+	}
+	if _, called := inputs["missing"]; called {
+		t.Error("the unknown tool was passed to the call function")
+	}
+	if r := responses["6"]; r.Error == nil {
+		t.Errorf("resources/list succeeded, want a method not found error")
+	}
+}
@@ -0,0 +1,116 @@
+package mcp
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"time"
+
+	"agent/jsonrpc"
+	"agent/tools"
+)
+
+// toolCallTimeout bounds a single call to an MCP tool.
+const toolCallTimeout = 5 * time.Minute
+
+// maxToolNameLength is the longest tool name the Anthropic API accepts.
+const maxToolNameLength = 64
+
+var invalidNameChars = regexp.MustCompile(`[^a-zA-Z0-9_-]`)
+
+// ToolName returns the name under which a server's tool is exposed to the
+// model: mcp__<server>__<tool>, with characters the API rejects replaced and
+// overlong names shortened with a hash suffix to keep them unique.
+func ToolName(server, tool string) string {
+	name := "mcp__" + invalidNameChars.ReplaceAllString(server, "_") + "__" + invalidNameChars.ReplaceAllString(tool, "_")
+	if len(name) <= maxToolNameLength {
+		return name
+	}
+	sum := sha256.Sum256([]byte(server + "/" + tool))
+	suffix := "_" + hex.EncodeToString(sum[:4])
+	return name[:maxToolNameLength-len(suffix)] + suffix
+}
+
+// ToolDefinitions lists the tools of every connected server and returns
+// them as tool definitions. Servers whose tools cannot be listed or whose
+// schemas are invalid are reported in the error and skipped.
+func (m *Manager) ToolDefinitions(ctx context.Context) ([]tools.ToolDefinition, error) {
+	var definitions []tools.ToolDefinition
+	var errs []error
+	for _, name := range m.ServerNames() {
+		client, err := m.Client(name)
+		if err != nil {
+			continue
+		}
+		if !client.HasCapability("tools") {
+			continue
+		}
+		serverTools, err := client.ListTools(ctx)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("mcp server %s: failed to list tools: %w", name, err))
+			continue
+		}
+		for _, tool := range serverTools {
+			def, err := toolDefinition(name, client, tool)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("mcp server %s: tool %s: %w", name, tool.Name, err))
+				continue
+			}
+			definitions = append(definitions, def)
+		}
+	}
+	return definitions, errors.Join(errs...)
+}
+
+// toolDefinition wraps a server's tool as a tool definition.
+func toolDefinition(server string, client *Client, tool Tool) (tools.ToolDefinition, error) {
+	schema, err := tools.SchemaFromJSON(tool.InputSchema)
+	if err != nil {
+		return tools.ToolDefinition{}, err
+	}
+	description := tool.Description
+	if description == "" {
+		description = tool.Name
+	}
+	return tools.ToolDefinition{
+		Name:        ToolName(server, tool.Name),
+		Description: fmt.Sprintf("[MCP server %s] %s", server, description),
+		InputSchema: schema,
+		Function: func(input json.RawMessage) (string, error) {
+			return callTool(server, client, tool.Name, input)
+		},
+		ReadOnly: tool.Annotations != nil && tool.Annotations.ReadOnlyHint,
+	}, nil
+}
+
+// callTool calls a server's tool and maps protocol and tool errors to Go errors.
+func callTool(server string, client *Client, name string, input json.RawMessage) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), toolCallTimeout)
+	defer cancel()
+
+	result, err := client.CallTool(ctx, name, input)
+	if err != nil {
+		var rpcErr *jsonrpc.Error
+		switch {
+		case errors.As(err, &rpcErr):
+			return "", fmt.Errorf("mcp server %s rejected the call to %s: %s", server, name, rpcErr.Message)
+		case errors.Is(err, context.DeadlineExceeded):
+			return "", fmt.Errorf("mcp server %s did not answer the call to %s within %s", server, name, toolCallTimeout)
+		case errors.Is(err, jsonrpc.ErrClosed):
+			return "", fmt.Errorf("mcp server %s has disconnected", server)
+		}
+		return "", fmt.Errorf("mcp server %s: %w", server, err)
+	}
+	if result.IsError {
+		text := result.Text()
+		if text == "" {
+			text = "tool reported an error without a message"
+		}
+		return "", errors.New(text)
+	}
+	return result.Text(), nil
+}
@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"os"
+	"os/signal"
+
+	"agent/agent"
+	"agent/config"
+	"agent/mcp"
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// mcpServe runs the agent's tools as an MCP server on stdin and stdout.
+// MCP servers from the config are not connected to, so that an agent
+// configured to use itself does not start copies of itself recursively.
+func mcpServe(cfg *config.Config, args []string) error {
+	flags := flag.NewFlagSet("mcp-serve", flag.ExitOnError)
+	delegate := flags.Bool("delegate", false, "also offer a delegate_task tool that runs a full agent session (requires ANTHROPIC_API_KEY)")
+	flags.Parse(args)
+
+	client := anthropic.NewClient()
+	env := setup(cfg, &client, false)
+	defer env.Close()
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+	defer stop()
+	return newMCPServer(env.agent, *delegate).Serve(ctx, os.Stdin, os.Stdout)
+}
+
+// newMCPServer returns a server offering a's tools, and delegate_task if
+// delegate is set.
+func newMCPServer(a *agent.Agent, delegate bool) *mcp.Server {
+	// The delegated session gets the agent's tools but not delegate_task
+	// itself, so it cannot recurse.
+	delegateTask := a.DelegateTaskDefinition()
+	defs := a.Tools()
+	if delegate {
+		defs = append(defs, delegateTask)
+	}
+
+	// The server only calls tools it lists, so delegate_task is only reached
+	// when enabled.
+	call := func(ctx context.Context, name string, input json.RawMessage) (string, error) {
+		if name == delegateTask.Name {
+			return delegateTask.Function(input)
+		}
+		return a.ExecuteTool(name, input)
+	}
+
+	return mcp.NewServer(
+		mcp.Implementation{Name: config.AppName, Version: "0.1.0"},
+		"Tools for reading, searching and editing files in the workspace. Files must be read with read_file before they can be edited.",
+		defs,
+		call,
+	)
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"agent/agent"
+	"agent/tools"
+)
+
+// listTools asks a server for the agent's tools, with or without -delegate,
+// and returns their names along with the error for calling delegate_task.
+func listTools(t *testing.T, delegate bool) ([]string, string) {
+	t.Helper()
+	a := agent.NewAgent(nil, nil, []tools.ToolDefinition{tools.ReadFileDefinition})
+	in := strings.NewReader(`{"jsonrpc": "2.0", "id": 1, "method": "tools/list"}
+{"jsonrpc": "2.0", "id": 2, "method": "tools/call", "params": {"name": "delegate_task", "arguments": {}}}
+`)
+	var out bytes.Buffer
+	if err := newMCPServer(a, delegate).Serve(context.Background(), in, &out); err != nil {
+		t.Fatalf("Serve: %v", err)
+	}
+
+	var names []string
+	callErr := ""
+	scanner := bufio.NewScanner(&out)
+	for scanner.Scan() {
+		var r struct {
+			ID     int `json:"id"`
+			Result struct {
+				Tools []struct {
+					Name string `json:"name"`
+				} `json:"tools"`
+			} `json:"result"`
+			Error *struct {
+				Message string `json:"message"`
+			} `json:"error"`
+		}
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatal(err)
+		}
+		switch {
+		case r.ID == 1:
+			for _, tool := range r.Result.Tools {
+				names = append(names, tool.Name)
+			}
+		case r.ID == 2 && r.Error != nil:
+			callErr = r.Error.Message
+		}
+	}
+	return names, callErr
+}
+
+func TestMCPServeDelegate(t *testing.T) {
+	names, callErr := listTools(t, false)
+	if strings.Join(names, ",") != "read_file" {
+		t.Errorf("tools without -delegate = %v, want only read_file", names)
+	}
+	if !strings.Contains(callErr, "unknown tool") {
+		t.Errorf("calling delegate_task without -delegate: error = %q, want unknown tool", callErr)
+	}
+
+	names, callErr = listTools(t, true)
+	if strings.Join(names, ",") != "read_file,delegate_task" {
+		t.Errorf("tools with -delegate = %v, want read_file and delegate_task", names)
+	}
+	// The call reaches the tool, which rejects the empty task.
+	if callErr != "" {
+		t.Errorf("calling delegate_task with -delegate: error = %q, want a tool result", callErr)
+	}
+}
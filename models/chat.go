@@ -1,13 +1,14 @@
 package models
 
 import (
-	"agent/logger"
 	"fmt"
 	"strings"
 
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/viewport"
 	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/glamour"
 	"github.com/charmbracelet/lipgloss"
 )
 
@@ -16,6 +17,8 @@ const (
 	aiPrefix           = "AI: "
 	claudePrefix       = "Claude: "
 	claudeErrorPrefix  = "Claude (error): "
+	systemPrefix       = "System: "
+	toolPrefix         = "Tool: "
 	paddingWidth       = 6
 	minContentWidth    = 20
 	minViewportHeight  = 5
@@ -30,19 +33,35 @@ var (
 
 // chatModel holds state for the chat panel.
 type chatModel struct {
-	textarea textarea.Model
-	viewport viewport.Model
-	messages []string
-	width    int
-	height   int
+	textarea      textarea.Model
+	viewport      viewport.Model
+	messages      []string
+	width         int
+	height        int
+	maxInputLines int // the input grows with its content up to this height
+	history       *inputHistory
+	search        *historySearch // set during a reverse search of the history
+	rendered      []renderedMessage
+	raw           bool   // show replies as raw text instead of rendered markdown
+	markdownStyle string // glamour style name or file
+	markdown      *glamour.TermRenderer
+	markdownWidth int                   // the width markdown wraps at
+	codeMarkdown  *glamour.TermRenderer // renders code blocks without wrapping
 }
 
 // newChatModel creates and initializes a new chatModel.
-func newChatModel() *chatModel {
+func newChatModel(maxInputLines int, history *inputHistory, markdownStyle string) *chatModel {
 	ta := textarea.New()
 	ta.Placeholder = defaultPlaceholder
 	ta.Prompt = ""
 	ta.ShowLineNumbers = false
+	// Enter sends the message, so newlines take a modifier. Terminals send
+	// Shift+Enter as Alt+Enter if they distinguish it at all.
+	ta.KeyMap.InsertNewline = key.NewBinding(key.WithKeys("alt+enter", "ctrl+j"))
+	// Ctrl+E opens the message in $EDITOR instead.
+	ta.KeyMap.LineEnd = key.NewBinding(key.WithKeys("end"))
+	// Pasted stack traces and the like may have any number of lines.
+	ta.MaxHeight = 0
 	ta.Focus()
 
 	// Initial default size, will be updated on WindowSizeMsg
@@ -65,12 +84,18 @@ func newChatModel() *chatModel {
 	vp := viewport.New(initialWidth, initialHeight-3) // Leave space for the textarea
 	vp.Style = viewportStyle
 
+	if maxInputLines < textareaHeight {
+		maxInputLines = textareaHeight
+	}
 	return &chatModel{
-		textarea: ta,
-		viewport: vp,
-		messages: make([]string, 0),
-		width:    initialWidth,
-		height:   initialHeight,
+		textarea:      ta,
+		viewport:      vp,
+		messages:      make([]string, 0),
+		width:         initialWidth,
+		height:        initialHeight,
+		maxInputLines: maxInputLines,
+		history:       history,
+		markdownStyle: markdownStyle,
 	}
 }
 
@@ -85,22 +110,57 @@ func (m *chatModel) updateSize(width, height int) {
 		contentWidth = minContentWidth
 	}
 
-	// Set viewport height to fill the available space
-	// Adjust to leave just enough room for textarea (1 line + border)
-	viewportHeight := height - 3            // Space for textarea and some padding
-	if viewportHeight < minViewportHeight { // Minimum reasonable height
-		viewportHeight = minViewportHeight
-	}
-
 	m.textarea.SetWidth(contentWidth)
 	m.viewport.Width = contentWidth
-	m.viewport.Height = viewportHeight
+	m.fitInput()
 
 	// Set viewport options for better rendering
 	m.viewport.SetContent(m.formatMessages())
 	m.viewport.YPosition = 0
 }
 
+// fitInput grows or shrinks the textarea to its content, up to
+// maxInputLines, and gives the viewport the rest of the height.
+func (m *chatModel) fitInput() {
+	lines := 0
+	width := m.textarea.Width()
+	for _, line := range strings.Split(m.textarea.Value(), "\n") {
+		// Wrapped lines take a row per width, plus one for the cursor when
+		// they fill the last row exactly.
+		lines++
+		if width > 0 {
+			lines += lipgloss.Width(line) / width
+		}
+	}
+	if lines > m.maxInputLines {
+		lines = m.maxInputLines
+	}
+	m.textarea.SetHeight(lines)
+	if m.search != nil {
+		lines++ // the search prompt
+	}
+
+	// Set viewport height to fill the available space
+	// Adjust to leave just enough room for textarea (its lines + border)
+	viewportHeight := m.height - 2 - lines  // Space for textarea and some padding
+	if viewportHeight < minViewportHeight { // Minimum reasonable height
+		viewportHeight = minViewportHeight
+	}
+	m.viewport.Height = viewportHeight
+}
+
+// setInput replaces the text being written with s.
+func (m *chatModel) setInput(s string) {
+	m.textarea.SetValue(s)
+	m.fitInput()
+}
+
+// resetInput clears the text being written.
+func (m *chatModel) resetInput() {
+	m.textarea.Reset()
+	m.fitInput()
+}
+
 // Init is required by Bubbletea but not used for chatModel.
 func (m *chatModel) Init() tea.Cmd {
 	// We'll let the main program handle initial window size
@@ -117,34 +177,65 @@ func (m *chatModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.updateSize(msg.Width, msg.Height)
 		return m, nil
 	case tea.KeyMsg:
-		if msg.Type == tea.KeyEnter && !msg.Alt {
-			userMsg := m.textarea.Value()
-			if userMsg != "" {
-				// Log user message
-				logger.LogMessage("User", userMsg)
-				m.messages = append(m.messages, userPrefix+userMsg)
-				m.viewport.SetContent(m.formatMessages())
-				m.textarea.Reset()
-				return m, nil
+		if m.search != nil {
+			m.updateSearch(msg)
+			return m, nil
+		}
+		switch {
+		case msg.Type == tea.KeyEnter && !msg.Alt && !msg.Paste:
+			// Enter sends the message, which MainModel does when it can;
+			// it never becomes a newline.
+			return m, nil
+		case msg.Type == tea.KeyCtrlR:
+			m.startSearch()
+			return m, nil
+		case msg.Type == tea.KeyUp && m.atFirstRow():
+			// Up and Down move through the sent messages once the cursor
+			// can go no further in the input.
+			if entry, ok := m.history.previous(m.textarea.Value()); ok {
+				m.setInput(entry)
 			}
+			return m, nil
+		case msg.Type == tea.KeyDown && m.atLastRow():
+			if entry, ok := m.history.next(); ok {
+				m.setInput(entry)
+				m.textarea, _ = m.textarea.Update(tea.KeyMsg{Type: tea.KeyCtrlHome})
+			}
+			return m, nil
 		}
 	}
 
 	m.textarea, cmd = m.textarea.Update(msg)
 	m.viewport, _ = m.viewport.Update(msg)
+	m.fitInput()
 	return m, cmd
 }
 
 // View renders the chat panel (viewport + textarea).
 func (m *chatModel) View() string {
+	input := m.textarea.View()
+	if m.search != nil {
+		input = m.searchPrompt() + "\n" + input
+	}
 	// Create a layout that takes the full available space
 	// Add a newline at the beginning to ensure top border is visible
 	return fmt.Sprintf("\n%s\n%s",
 		m.viewport.View(),
-		m.textarea.View(),
+		input,
 	)
 }
 
+// atFirstRow reports whether the cursor is on the input's first row.
+func (m *chatModel) atFirstRow() bool {
+	return m.textarea.Line() == 0 && m.textarea.LineInfo().RowOffset == 0
+}
+
+// atLastRow reports whether the cursor is on the input's last row.
+func (m *chatModel) atLastRow() bool {
+	info := m.textarea.LineInfo()
+	return m.textarea.Line() == m.textarea.LineCount()-1 && info.RowOffset >= info.Height-1
+}
+
 // formatMessages prepares the chat messages for display in the viewport.
 func (m *chatModel) formatMessages() string {
 	var formattedContent strings.Builder
@@ -156,52 +247,74 @@ func (m *chatModel) formatMessages() string {
 		contentWidth = minContentWidth // Minimum reasonable width
 	}
 
-	for _, msg := range m.messages {
-		// Split the message into parts (e.g., "User: Hello")
-		parts := strings.SplitN(msg, ": ", 2)
-		if len(parts) != 2 {
-			// If not in expected format, just add the message
-			formattedContent.WriteString(wrapText(msg, contentWidth))
-			formattedContent.WriteString("\n\n")
-			continue
+	for i, msg := range m.messages {
+		if i == len(m.rendered) {
+			m.rendered = append(m.rendered, renderedMessage{})
 		}
+		cached := &m.rendered[i]
+		if cached.source != msg || cached.width != contentWidth || cached.raw != m.raw {
+			*cached = renderedMessage{source: msg, width: contentWidth, raw: m.raw, text: m.formatMessage(msg, contentWidth)}
+		}
+		formattedContent.WriteString(cached.text)
+	}
 
-		// Get the sender and content
-		sender, content := parts[0], parts[1]
+	return formattedContent.String()
+}
 
-		// Add the sender with formatting
-		if sender == "User" {
-			formattedContent.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("69")).Render(sender) + ": ")
-		} else {
-			formattedContent.WriteString(lipgloss.NewStyle().Foreground(lipgloss.Color("176")).Render(sender) + ": ")
-		}
+// formatMessage formats one message for display in the viewport.
+func (m *chatModel) formatMessage(msg string, contentWidth int) string {
+	var formattedContent strings.Builder
 
-		// Account for the prefix in our width calculation
-		// We have to subtract the length of the prefix from our available width
-		prefixLen := len(sender) + 2 // +2 for ": "
-		adjustedWidth := contentWidth
-		if prefixLen < contentWidth {
-			adjustedWidth = contentWidth - prefixLen
+	// Split the message into parts (e.g., "User: Hello")
+	parts := strings.SplitN(msg, ": ", 2)
+	if len(parts) != 2 {
+		// If not in expected format, just add the message
+		return wrapText(msg, contentWidth) + "\n\n"
+	}
+
+	// Get the sender and content
+	sender, content := parts[0], parts[1]
+
+	// Add the sender with formatting
+	label := lipgloss.NewStyle().Foreground(lipgloss.Color("176")).Render(sender)
+	if sender == "User" {
+		label = lipgloss.NewStyle().Foreground(lipgloss.Color("69")).Render(sender)
+	}
+
+	// Replies are rendered as markdown below the sender, falling back to
+	// plain text if that fails
+	if isMarkdownSender(sender) && !m.raw {
+		if rendered, err := m.renderMarkdown(content, contentWidth); err == nil {
+			return label + ":\n" + rendered + "\n\n"
 		}
+	}
+	formattedContent.WriteString(label + ": ")
+
+	// Account for the prefix in our width calculation
+	// We have to subtract the length of the prefix from our available width
+	prefixLen := len(sender) + 2 // +2 for ": "
+	adjustedWidth := contentWidth
+	if prefixLen < contentWidth {
+		adjustedWidth = contentWidth - prefixLen
+	}
+
+	// Wrap the content text and add it
+	wrappedContent := wrapText(content, adjustedWidth)
+
+	// For the first line, we'll use it as is, but for subsequent lines
+	// we need to add proper indentation to align with the first line's content
+	lines := strings.Split(wrappedContent, "\n")
+	if len(lines) > 0 {
+		formattedContent.WriteString(lines[0])
 
-		// Wrap the content text and add it
-		wrappedContent := wrapText(content, adjustedWidth)
-
-		// For the first line, we'll use it as is, but for subsequent lines
-		// we need to add proper indentation to align with the first line's content
-		lines := strings.Split(wrappedContent, "\n")
-		if len(lines) > 0 {
-			formattedContent.WriteString(lines[0])
-
-			// Add proper indentation for subsequent lines
-			if len(lines) > 1 {
-				indent := strings.Repeat(" ", prefixLen)
-				for _, line := range lines[1:] {
-					formattedContent.WriteString("\n" + indent + line)
-				}
+		// Add proper indentation for subsequent lines
+		if len(lines) > 1 {
+			indent := strings.Repeat(" ", prefixLen)
+			for _, line := range lines[1:] {
+				formattedContent.WriteString("\n" + indent + line)
 			}
-			formattedContent.WriteString("\n\n")
 		}
+		formattedContent.WriteString("\n\n")
 	}
 
 	return formattedContent.String()
@@ -215,15 +328,23 @@ func boolToInt(b bool) int {
 	return 0
 }
 
-// AddAIMessage adds an AI response to the chat and logs it.
+// AddAIMessage adds an AI response to the chat.
 func (m *chatModel) AddAIMessage(content string) {
-	logger.LogMessage("AI", content)
 	m.messages = append(m.messages, aiPrefix+content)
 	m.viewport.SetContent(m.formatMessages())
 }
 
-// AddMessage adds a message to the chat with the given sender and content.
-func (m *chatModel) AddMessage(sender, content string) {
+// AddMessage adds a message to the chat with the given sender and content,
+// and returns its index for SetMessage.
+func (m *chatModel) AddMessage(sender, content string) int {
+	m.messages = append(m.messages, "")
+	idx := len(m.messages) - 1
+	m.SetMessage(idx, sender, content)
+	return idx
+}
+
+// SetMessage replaces the message at idx.
+func (m *chatModel) SetMessage(idx int, sender, content string) {
 	prefix := userPrefix
 	switch sender {
 	case "AI":
@@ -232,7 +353,11 @@ func (m *chatModel) AddMessage(sender, content string) {
 		prefix = claudePrefix
 	case "Claude (error)":
 		prefix = claudeErrorPrefix
+	case "System":
+		prefix = systemPrefix
+	case "Tool":
+		prefix = toolPrefix
 	}
-	m.messages = append(m.messages, prefix+content)
+	m.messages[idx] = prefix + content
 	m.viewport.SetContent(m.formatMessages())
 }
@@ -0,0 +1,252 @@
+package models
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"agent/logger"
+	"agent/tracing"
+	"agent/transcript"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// commandTimeout bounds slash commands that talk to external servers.
+const commandTimeout = 30 * time.Second
+
+// commandResultMsg delivers the outcome of a slash command.
+type commandResultMsg struct {
+	Message string // shown in the chat as a system message
+	Tab     string // if set, Content is opened in a codeview tab with this name
+	Content string
+	Input   string // if set, replaces the chat input so the user can edit and send it
+	Err     error
+}
+
+// command is a slash command typed into the chat input.
+type command struct {
+	usage       string
+	description string
+	run         func(m *MainModel, args []string) tea.Msg
+}
+
+var commands map[string]command
+
+func init() {
+	commands = map[string]command{
+		"help": {
+			usage:       "/help",
+			description: "List the available commands.",
+			run:         helpCommand,
+		},
+		"export": {
+			usage:       "/export [md|html|json] [path]",
+			description: "Export this session's transcript, by default as Markdown to session_<id>.md.",
+			run:         exportCommand,
+		},
+		"raw": {
+			usage:       "/raw",
+			description: "Toggle between rendered markdown and the raw text of replies.",
+			run:         func(m *MainModel, args []string) tea.Msg { return toggleRawMsg{} },
+		},
+		"mcp": {
+			usage:       "/mcp [read <server> <uri> | prompt <server> <name> [arg=value...]]",
+			description: "List MCP servers with their resources and prompts, open a resource, or load a prompt into the input.",
+			run:         mcpCommand,
+		},
+	}
+}
+
+// isCommand reports whether input is a slash command rather than a message.
+func isCommand(input string) bool {
+	return strings.HasPrefix(input, "/") && !strings.HasPrefix(input, "//")
+}
+
+// runCommand parses and runs a slash command asynchronously.
+func (m *MainModel) runCommand(input string) tea.Cmd {
+	fields := strings.Fields(strings.TrimPrefix(input, "/"))
+	if len(fields) == 0 {
+		return nil
+	}
+	cmd, ok := commands[fields[0]]
+	if !ok {
+		return func() tea.Msg {
+			return commandResultMsg{Err: fmt.Errorf("unknown command /%s; type /help for a list", fields[0])}
+		}
+	}
+	args := fields[1:]
+	return func() tea.Msg {
+		_, span := tracing.Start(context.Background(), "command", "command", fields[0], "args", len(args))
+		defer span.End()
+		msg := cmd.run(m, args)
+		if result, ok := msg.(commandResultMsg); ok {
+			span.RecordError(result.Err)
+		}
+		return msg
+	}
+}
+
+func helpCommand(m *MainModel, args []string) tea.Msg {
+	names := make([]string, 0, len(commands))
+	for name := range commands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var out strings.Builder
+	for _, name := range names {
+		fmt.Fprintf(&out, "%s\n    %s\n", commands[name].usage, commands[name].description)
+	}
+	return commandResultMsg{Message: strings.TrimRight(out.String(), "\n")}
+}
+
+func exportCommand(m *MainModel, args []string) tea.Msg {
+	if len(args) > 2 {
+		return commandResultMsg{Err: fmt.Errorf("usage: /export [md|html|json] [path]")}
+	}
+	format, path := "md", ""
+	if len(args) > 0 {
+		format = args[0]
+	}
+	if len(args) > 1 {
+		path = args[1]
+	}
+	if logger.SessionID() == "" {
+		return commandResultMsg{Err: fmt.Errorf("this session is not being logged")}
+	}
+	session, err := logger.FindSession(logger.Dir(), logger.SessionID())
+	if err != nil {
+		return commandResultMsg{Err: err}
+	}
+	path, err = transcript.Export(session, format, path)
+	if err != nil {
+		return commandResultMsg{Err: err}
+	}
+	return commandResultMsg{Message: fmt.Sprintf("Exported the transcript to %s", path)}
+}
+
+func mcpCommand(m *MainModel, args []string) tea.Msg {
+	if m.MCP == nil {
+		return commandResultMsg{Err: fmt.Errorf("no MCP servers are configured")}
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+	defer cancel()
+
+	if len(args) == 0 {
+		return mcpOverview(ctx, m)
+	}
+	switch args[0] {
+	case "read":
+		if len(args) != 3 {
+			return commandResultMsg{Err: fmt.Errorf("usage: /mcp read <server> <uri>")}
+		}
+		return mcpReadResource(ctx, m, args[1], args[2])
+	case "prompt":
+		if len(args) < 3 {
+			return commandResultMsg{Err: fmt.Errorf("usage: /mcp prompt <server> <name> [arg=value...]")}
+		}
+		return mcpGetPrompt(ctx, m, args[1], args[2], args[3:])
+	}
+	return commandResultMsg{Err: fmt.Errorf("unknown /mcp subcommand %q", args[0])}
+}
+
+// mcpOverview lists every server with its status, resources and prompts.
+func mcpOverview(ctx context.Context, m *MainModel) tea.Msg {
+	var out strings.Builder
+	for _, name := range m.MCP.ServerNames() {
+		client, err := m.MCP.Client(name)
+		if err != nil {
+			fmt.Fprintf(&out, "%s: %v\n\n", name, err)
+			continue
+		}
+		info := client.ServerInfo()
+		fmt.Fprintf(&out, "%s: connected to %s %s\n", name, info.Name, info.Version)
+
+		if client.HasCapability("resources") {
+			resources, err := client.ListResources(ctx)
+			if err != nil {
+				fmt.Fprintf(&out, "  resources: %v\n", err)
+			} else {
+				fmt.Fprintf(&out, "  resources (%d):\n", len(resources))
+				for _, r := range resources {
+					fmt.Fprintf(&out, "    %s  %s\n", r.URI, r.Name)
+				}
+			}
+		}
+		if client.HasCapability("prompts") {
+			prompts, err := client.ListPrompts(ctx)
+			if err != nil {
+				fmt.Fprintf(&out, "  prompts: %v\n", err)
+			} else {
+				fmt.Fprintf(&out, "  prompts (%d):\n", len(prompts))
+				for _, p := range prompts {
+					var params []string
+					for _, arg := range p.Arguments {
+						if arg.Required {
+							params = append(params, arg.Name+"=…")
+						} else {
+							params = append(params, "["+arg.Name+"=…]")
+						}
+					}
+					fmt.Fprintf(&out, "    %s %s  %s\n", p.Name, strings.Join(params, " "), p.Description)
+				}
+			}
+		}
+		out.WriteString("\n")
+	}
+	out.WriteString("Open a resource with /mcp read <server> <uri>, or load a prompt with /mcp prompt <server> <name> [arg=value...].\n")
+	return commandResultMsg{Message: "MCP servers are listed in the side panel.", Tab: "MCP servers", Content: out.String()}
+}
+
+// mcpReadResource opens a resource's contents in a codeview tab.
+func mcpReadResource(ctx context.Context, m *MainModel, server, uri string) tea.Msg {
+	client, err := m.MCP.Client(server)
+	if err != nil {
+		return commandResultMsg{Err: err}
+	}
+	contents, err := client.ReadResource(ctx, uri)
+	if err != nil {
+		return commandResultMsg{Err: fmt.Errorf("failed to read %s: %w", uri, err)}
+	}
+	var out strings.Builder
+	for _, c := range contents {
+		if c.Text != "" {
+			out.WriteString(c.Text)
+		} else {
+			fmt.Fprintf(&out, "[binary content: %s, %d bytes base64]", c.MimeType, len(c.Blob))
+		}
+		out.WriteString("\n")
+	}
+	return commandResultMsg{Tab: uri, Content: out.String()}
+}
+
+// mcpGetPrompt expands a prompt and places its text in the chat input.
+func mcpGetPrompt(ctx context.Context, m *MainModel, server, name string, args []string) tea.Msg {
+	client, err := m.MCP.Client(server)
+	if err != nil {
+		return commandResultMsg{Err: err}
+	}
+	arguments := make(map[string]string)
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if !ok {
+			return commandResultMsg{Err: fmt.Errorf("prompt argument %q must have the form name=value", arg)}
+		}
+		arguments[key] = value
+	}
+	result, err := client.GetPrompt(ctx, name, arguments)
+	if err != nil {
+		return commandResultMsg{Err: fmt.Errorf("failed to get prompt %s: %w", name, err)}
+	}
+
+	var parts []string
+	for _, msg := range result.Messages {
+		parts = append(parts, msg.Content.String())
+	}
+	return commandResultMsg{
+		Message: fmt.Sprintf("Loaded prompt %s from %s into the input; edit it or press Enter to send.", name, server),
+		Input:   strings.Join(parts, "\n\n"),
+	}
+}
@@ -0,0 +1,57 @@
+package models
+
+import (
+	"os"
+	"os/exec"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// editorFinishedMsg carries the message composed in an external editor.
+type editorFinishedMsg struct {
+	Text string
+	Err  error
+}
+
+// openEditor suspends the UI and opens the message being written in the
+// user's editor: $VISUAL, $EDITOR or vi. The result replaces the input but
+// is not sent, so that it can be checked first.
+func (m *chatModel) openEditor() tea.Cmd {
+	file, err := os.CreateTemp("", "agent-message-*.md")
+	if err != nil {
+		return func() tea.Msg { return editorFinishedMsg{Err: err} }
+	}
+	path := file.Name()
+	_, err = file.WriteString(m.textarea.Value())
+	if closeErr := file.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		os.Remove(path)
+		return func() tea.Msg { return editorFinishedMsg{Err: err} }
+	}
+
+	editor := os.Getenv("VISUAL")
+	if editor == "" {
+		editor = os.Getenv("EDITOR")
+	}
+	// The variables may hold arguments too, as in "code --wait".
+	args := strings.Fields(editor)
+	if len(args) == 0 {
+		args = []string{"vi"}
+	}
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		defer os.Remove(path)
+		if err != nil {
+			return editorFinishedMsg{Err: err}
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return editorFinishedMsg{Err: err}
+		}
+		// Editors end files with a newline the user did not type.
+		return editorFinishedMsg{Text: strings.TrimRight(string(data), "\n")}
+	})
+}
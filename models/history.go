@@ -0,0 +1,222 @@
+package models
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"agent/logger"
+	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// maxHistory bounds the number of sent messages kept in the input history.
+const maxHistory = 1000
+
+var searchPromptStyle = lipgloss.NewStyle().Foreground(lipgloss.Color("240"))
+
+// inputHistory holds the messages sent from the chat input, oldest first.
+// It is appended to a file as messages are sent, so that it is kept across
+// runs.
+type inputHistory struct {
+	entries []string
+	path    string // "" if the history is not saved
+	lines   int    // lines in the file, which is compacted as it grows
+	pos     int    // entry being shown; len(entries) when not browsing
+	draft   string // the unsent input, shown again after the newest entry
+}
+
+// loadHistory reads the history saved in path, if any.
+func loadHistory(path string) *inputHistory {
+	h := &inputHistory{path: path}
+	if path != "" {
+		if file, err := os.Open(path); err == nil {
+			scanner := bufio.NewScanner(file)
+			scanner.Buffer(nil, 1<<20)
+			for scanner.Scan() {
+				var entry string
+				if json.Unmarshal(scanner.Bytes(), &entry) == nil && entry != "" {
+					h.entries = append(h.entries, entry)
+				}
+				h.lines++
+			}
+			file.Close()
+		}
+	}
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+	}
+	h.pos = len(h.entries)
+	return h
+}
+
+// add records a sent message, unless it repeats the previous one, and stops
+// browsing.
+func (h *inputHistory) add(entry string) {
+	defer h.reset()
+	if strings.TrimSpace(entry) == "" || (len(h.entries) > 0 && h.entries[len(h.entries)-1] == entry) {
+		return
+	}
+	h.entries = append(h.entries, entry)
+	if len(h.entries) > maxHistory {
+		h.entries = h.entries[len(h.entries)-maxHistory:]
+	}
+	if h.path == "" {
+		return
+	}
+	if err := h.save(entry); err != nil {
+		logger.Error("failed to save input history", err, "path", h.path)
+	}
+}
+
+// save appends entry to the file, or rewrites the file with the kept
+// entries once it holds twice as many as are kept.
+func (h *inputHistory) save(entry string) error {
+	if err := os.MkdirAll(filepath.Dir(h.path), 0o700); err != nil {
+		return err
+	}
+	if h.lines >= 2*maxHistory {
+		var data []byte
+		for _, e := range h.entries {
+			line, _ := json.Marshal(e)
+			data = append(append(data, line...), '\n')
+		}
+		tmp := h.path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0o600); err != nil {
+			return err
+		}
+		h.lines = len(h.entries)
+		return os.Rename(tmp, h.path)
+	}
+	file, err := os.OpenFile(h.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	line, _ := json.Marshal(entry)
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		file.Close()
+		return err
+	}
+	h.lines++
+	return file.Close()
+}
+
+// reset stops browsing.
+func (h *inputHistory) reset() {
+	h.pos = len(h.entries)
+	h.draft = ""
+}
+
+// previous returns the entry before the one shown, keeping current as the
+// draft when browsing starts. It returns false at the oldest entry.
+func (h *inputHistory) previous(current string) (string, bool) {
+	if h.pos == 0 {
+		return "", false
+	}
+	if h.pos == len(h.entries) {
+		h.draft = current
+	}
+	h.pos--
+	return h.entries[h.pos], true
+}
+
+// next returns the entry after the one shown, or the draft after the newest
+// entry. It returns false when not browsing.
+func (h *inputHistory) next() (string, bool) {
+	if h.pos >= len(h.entries) {
+		return "", false
+	}
+	h.pos++
+	if h.pos == len(h.entries) {
+		draft := h.draft
+		h.draft = ""
+		return draft, true
+	}
+	return h.entries[h.pos], true
+}
+
+// search returns the index of the newest entry before index before that
+// contains query, or -1.
+func (h *inputHistory) search(query string, before int) int {
+	for i := min(before, len(h.entries)) - 1; i >= 0; i-- {
+		if strings.Contains(h.entries[i], query) {
+			return i
+		}
+	}
+	return -1
+}
+
+// historySearch is the state of a reverse incremental search of the input
+// history, as started with Ctrl+R.
+type historySearch struct {
+	query string
+	match int    // index of the entry shown, or -1
+	draft string // the input before the search, restored if it is cancelled
+}
+
+// startSearch starts a reverse search of the input history.
+func (m *chatModel) startSearch() {
+	m.search = &historySearch{match: -1, draft: m.textarea.Value()}
+	m.fitInput()
+}
+
+// updateSearch handles a key during a reverse search. Typing narrows the
+// search, Ctrl+R finds the next older match, Esc or Ctrl+G cancel it and
+// any other key, such as Enter, leaves the match in the input.
+func (m *chatModel) updateSearch(msg tea.KeyMsg) {
+	s := m.search
+	switch msg.Type {
+	case tea.KeyCtrlR:
+		if s.query != "" && s.match >= 0 {
+			if i := m.history.search(s.query, s.match); i >= 0 {
+				s.match = i
+			}
+		}
+	case tea.KeyRunes, tea.KeySpace:
+		s.query += string(msg.Runes)
+		// The entry shown is kept if it still matches.
+		from := len(m.history.entries)
+		if s.match >= 0 {
+			from = s.match + 1
+		}
+		s.match = m.history.search(s.query, from)
+	case tea.KeyBackspace:
+		if s.query == "" {
+			return
+		}
+		runes := []rune(s.query)
+		s.query = string(runes[:len(runes)-1])
+		s.match = -1
+		if s.query != "" {
+			s.match = m.history.search(s.query, len(m.history.entries))
+		}
+	case tea.KeyEsc, tea.KeyCtrlG:
+		m.search = nil
+		m.setInput(s.draft)
+		return
+	default:
+		m.search = nil
+		if s.match >= 0 {
+			// Up and Down go on from the match.
+			m.history.pos, m.history.draft = s.match, s.draft
+		}
+		m.fitInput()
+		return
+	}
+	if s.match >= 0 {
+		m.setInput(m.history.entries[s.match])
+	} else if s.query == "" {
+		m.setInput(s.draft)
+	}
+}
+
+// searchPrompt renders the line shown above the input during a search.
+func (m *chatModel) searchPrompt() string {
+	prompt := "reverse-i-search"
+	if m.search.query != "" && m.search.match < 0 {
+		prompt = "failing " + prompt
+	}
+	return searchPromptStyle.Render(prompt+": ") + m.search.query
+}
@@ -2,19 +2,24 @@ package models
 
 import (
 	"agent/agent"
-	"agent/logger"
+	"agent/mcp"
 	"context"
-	"encoding/json"
+	"fmt"
 	"io/ioutil"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
 )
 
-// claudeResponseMsg is used to deliver responses from Claude (or errors) asynchronously.
+// claudeResponseMsg is sent when the agent has finished working on a message.
 type claudeResponseMsg struct {
-	Text string
-	Err  error
+	Err error
+}
+
+// agentEventMsg delivers the agent's progress while it works on a message.
+type agentEventMsg struct {
+	Event agent.Event
 }
 
 // openFileMsg is used to deliver file open requests.
@@ -22,26 +27,12 @@ type openFileMsg struct {
 	FileName string
 }
 
-// toolResultMsg is used to deliver tool execution results asynchronously.
-type toolResultMsg struct {
-	ID     string
-	Name   string
-	Result string
-	Err    error
-}
-
 type ToolStatus struct {
-	Name   string
-	Status string // "pending", "done", "error"
-	Result string
-	Err    error
-}
-
-// toolRequest is used to dispatch tool requests asynchronously.
-type toolRequest struct {
-	ID   string
-	Name string
-	Args map[string]interface{}
+	Name     string
+	Status   string // "pending", "done", "error"
+	Result   string
+	Err      error
+	Duration time.Duration
 }
 
 // MainModel is the root model for the Bubbletea application.
@@ -50,7 +41,13 @@ type MainModel struct {
 	codeview           *codeviewModel
 	sidebar            *sidebarModel
 	Agent              *agent.Agent
-	conversation       []string // Conversation history as plain strings for now
+	MCP                *mcp.Manager // nil if no MCP servers are configured
+	Replay             []string     // messages sent one after another on start, to replay a session
+	MaxInputLines      int          // how far the message input grows before it scrolls
+	HistoryFile        string       // where sent messages are kept for recall; "" keeps them for this run only
+	MarkdownStyle      string       // glamour style of replies, by default "dark"
+	Notices            []string     // shown as system messages on start
+	conversation       []string     // Conversation history as plain strings for now
 	quitting           bool
 	waitingForClaude   bool
 	width              int    // Terminal width
@@ -58,11 +55,14 @@ type MainModel struct {
 	focusedPane        string // "sidebar" or "chat"
 	sidebarShowingFile bool
 	inFlightTools      map[string]ToolStatus // Track running tool commands
+	toolLines          map[string]int        // chat message index of each tool call's status line
+	agentEvents        chan tea.Msg          // progress from the agent, read by waitForAgentEvent
+	replaying          bool                  // set while messages from Replay are being sent
 }
 
 // Init sets up the initial state for the main model.
 func (m *MainModel) Init() tea.Cmd {
-	m.chat = newChatModel()
+	m.chat = newChatModel(m.MaxInputLines, loadHistory(m.HistoryFile), m.MarkdownStyle)
 	m.conversation = []string{}
 	m.waitingForClaude = false
 	m.sidebar = newSidebarModelFromDir(".")
@@ -70,9 +70,18 @@ func (m *MainModel) Init() tea.Cmd {
 	m.codeview = NewCodeViewModel(80, 20)
 	m.focusedPane = "chat"
 	m.inFlightTools = make(map[string]ToolStatus)
+	m.toolLines = make(map[string]int)
+	m.agentEvents = make(chan tea.Msg)
+	for _, notice := range m.Notices {
+		m.chat.AddMessage("System", notice)
+	}
 
 	cmds := []tea.Cmd{
 		tea.EnterAltScreen,
+		m.waitForAgentEvent(),
+	}
+	if cmd := m.sendNextReplay(); cmd != nil {
+		cmds = append(cmds, cmd)
 	}
 
 	// Get initial window size
@@ -137,13 +146,22 @@ func (m *MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			}
 			return m, nil
 		}
-		if msg.Type == tea.KeyEnter && !m.waitingForClaude {
+		if msg.Type == tea.KeyCtrlE && m.focusedPane == "chat" && m.chat.search == nil {
+			return m, m.chat.openEditor()
+		}
+		if msg.Type == tea.KeyEnter && !msg.Alt && !m.waitingForClaude && m.chat.search == nil {
 			input := m.chat.textarea.Value()
+			if isCommand(input) {
+				m.chat.history.add(input)
+				m.chat.resetInput()
+				m.chat.AddMessage("User", input)
+				return m, m.runCommand(input)
+			}
 			if input != "" {
 				m.conversation = append(m.conversation, "You: "+input)
-				m.chat.textarea.Reset()
+				m.chat.history.add(input)
+				m.chat.resetInput()
 				m.chat.AddMessage("User", input)
-				logger.LogMessage("User", input)
 				m.waitingForClaude = true
 				return m, m.sendToClaude(input)
 			}
@@ -162,41 +180,56 @@ func (m *MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			m.sidebarShowingFile = true
 		}
 		return m, nil
-	case claudeResponseMsg:
-		m.waitingForClaude = false
+	case commandResultMsg:
 		if msg.Err != nil {
-			m.conversation = append(m.conversation, "Claude (error): "+msg.Err.Error())
-			m.chat.AddMessage("Claude (error)", msg.Err.Error())
-			logger.LogMessage("Claude (error)", msg.Err.Error())
+			m.chat.AddMessage("System", msg.Err.Error())
+			return m, nil
+		}
+		if msg.Message != "" {
+			m.chat.AddMessage("System", msg.Message)
+		}
+		if msg.Tab != "" && m.codeview != nil {
+			m.codeview.OpenTab(msg.Tab, msg.Content)
+			m.sidebarShowingFile = true
+		}
+		if msg.Input != "" {
+			m.chat.setInput(msg.Input)
+		}
+		return m, nil
+	case toggleRawMsg:
+		m.chat.setRaw(!m.chat.raw)
+		if m.chat.raw {
+			m.chat.AddMessage("System", "Showing replies as raw text; /raw renders them again.")
 		} else {
-			m.conversation = append(m.conversation, "Claude: "+msg.Text)
-			m.chat.AddMessage("Claude", msg.Text)
-			logger.LogMessage("Claude", msg.Text)
+			m.chat.AddMessage("System", "Rendering replies as markdown.")
 		}
-	case toolRequest:
-		// Mark as pending
-		m.inFlightTools[msg.ID] = ToolStatus{Name: msg.Name, Status: "pending"}
-		return m, m.executeToolAsync(msg)
-	case toolResultMsg:
-		status := "done"
+		return m, nil
+	case editorFinishedMsg:
 		if msg.Err != nil {
-			status = "error"
+			m.chat.AddMessage("System", "Editor failed: "+msg.Err.Error())
+			return m, nil
 		}
-		m.inFlightTools[msg.ID] = ToolStatus{Name: msg.Name, Status: status, Result: msg.Result, Err: msg.Err}
-		if msg.Err == nil {
-			// Show result in codeview if relevant (for read_file, edit_file, list_files, etc.)
-			if m.codeview != nil {
-				m.codeview.OpenTab(msg.ID, msg.Result)
-				m.sidebarShowingFile = true
+		m.chat.setInput(msg.Text)
+		return m, nil
+	case agentEventMsg:
+		m.handleAgentEvent(msg.Event)
+		return m, m.waitForAgentEvent()
+	case claudeResponseMsg:
+		m.waitingForClaude = false
+		if msg.Err != nil {
+			m.conversation = append(m.conversation, "Claude (error): "+msg.Err.Error())
+			m.chat.AddMessage("Claude (error)", msg.Err.Error())
+			if len(m.Replay) > 0 {
+				m.chat.AddMessage("System", fmt.Sprintf("Replay stopped with %d messages left.", len(m.Replay)))
+				m.Replay, m.replaying = nil, false
 			}
-			// Always send tool result back to Claude for follow-up
-			return m, m.sendToClaude(msg.Result)
 		}
-		if msg.Err != nil && m.codeview != nil {
-			m.codeview.OpenTab(msg.ID, "[ERROR] "+msg.Err.Error())
-			m.sidebarShowingFile = true
+		next := m.sendNextReplay()
+		if next == nil && m.replaying {
+			m.replaying = false
+			m.chat.AddMessage("System", "Replay finished; press Ctrl+C to see the report.")
 		}
-		return m, nil
+		return m, tea.Batch(m.waitForAgentEvent(), next)
 	}
 	// Forward input to focused pane
 	if m.focusedPane == "sidebar" && m.sidebar != nil && !m.sidebarShowingFile {
@@ -211,45 +244,83 @@ func (m *MainModel) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	return m, nil
 }
 
-// sendToClaude sends a user message to Claude and returns a command that will deliver the response asynchronously.
+// sendNextReplay sends the next message being replayed, if any.
+func (m *MainModel) sendNextReplay() tea.Cmd {
+	if len(m.Replay) == 0 {
+		return nil
+	}
+	input := m.Replay[0]
+	m.Replay = m.Replay[1:]
+	m.conversation = append(m.conversation, "You: "+input)
+	m.chat.AddMessage("User", input)
+	m.waitingForClaude = true
+	m.replaying = true
+	return m.sendToClaude(input)
+}
+
+// sendToClaude hands a user message to the agent. Its progress, and finally
+// a claudeResponseMsg, arrive through the agent events channel.
 func (m *MainModel) sendToClaude(input string) tea.Cmd {
 	return func() tea.Msg {
 		if m.Agent == nil {
-			return claudeResponseMsg{Err: context.DeadlineExceeded}
-		}
-		ctx := context.Background()
-		resp, err := m.Agent.RunInference(ctx, input)
-		if err != nil {
-			return claudeResponseMsg{Err: err}
+			m.agentEvents <- claudeResponseMsg{Err: context.DeadlineExceeded}
+			return nil
 		}
-		// If there are tool uses, dispatch toolRequest for the first one
-		if len(resp.ToolUses) > 0 {
-			tu := resp.ToolUses[0]
-			return toolRequest{ID: tu.ID, Name: tu.Name, Args: tu.Args}
-		}
-		// Otherwise, return the first text response (if any)
-		if len(resp.Texts) > 0 {
-			return claudeResponseMsg{Text: resp.Texts[0]}
-		}
-		return claudeResponseMsg{Text: "[No Claude response]"}
+		err := m.Agent.Send(context.Background(), input, func(event agent.Event) {
+			m.agentEvents <- agentEventMsg{Event: event}
+		})
+		m.agentEvents <- claudeResponseMsg{Err: err}
+		return nil
 	}
 }
 
-// executeToolAsync executes a tool asynchronously and returns a command that will deliver the result.
-func (m *MainModel) executeToolAsync(req toolRequest) tea.Cmd {
+// waitForAgentEvent delivers the next message from the agent events channel.
+// It is re-issued after every such message, so exactly one is pending.
+func (m *MainModel) waitForAgentEvent() tea.Cmd {
 	return func() tea.Msg {
-		if m.Agent == nil {
-			return toolResultMsg{ID: req.ID, Name: req.Name, Err: context.DeadlineExceeded}
+		return <-m.agentEvents
+	}
+}
+
+// handleAgentEvent shows the agent's text and keeps one status line per tool
+// call up to date in the chat.
+func (m *MainModel) handleAgentEvent(event agent.Event) {
+	switch event.Kind {
+	case agent.EventText:
+		m.conversation = append(m.conversation, "Claude: "+event.Text)
+		m.chat.AddMessage("Claude", event.Text)
+	case agent.EventToolStart:
+		m.inFlightTools[event.Call.ID] = ToolStatus{Name: event.Call.Name, Status: "pending"}
+		m.toolLines[event.Call.ID] = m.chat.AddMessage("Tool", "… "+describeToolCall(event.Call))
+	case agent.EventToolDone:
+		status := ToolStatus{Name: event.Call.Name, Status: "done", Result: event.Result, Err: event.Err, Duration: event.Duration}
+		line := fmt.Sprintf("✓ %s (%s)", describeToolCall(event.Call), event.Duration.Round(time.Millisecond))
+		content := event.Result
+		if event.Err != nil {
+			status.Status = "error"
+			line = fmt.Sprintf("✗ %s: %v", describeToolCall(event.Call), event.Err)
+			content = "[ERROR] " + event.Err.Error()
 		}
-		// Marshal args to JSON
-		input, err := json.Marshal(req.Args)
-		if err != nil {
-			return toolResultMsg{ID: req.ID, Name: req.Name, Err: err}
+		m.inFlightTools[event.Call.ID] = status
+		if idx, ok := m.toolLines[event.Call.ID]; ok {
+			m.chat.SetMessage(idx, "Tool", line)
+			delete(m.toolLines, event.Call.ID)
 		}
-		// Call agent's executeTool (returns string, error)
-		result, err := m.Agent.ExecuteTool(req.Name, input)
-		return toolResultMsg{ID: req.ID, Name: req.Name, Result: result, Err: err}
+		// Show the result in codeview (for read_file, edit_file, list_files, etc.)
+		if m.codeview != nil {
+			m.codeview.OpenTab(event.Call.ID, content)
+			m.sidebarShowingFile = true
+		}
+	}
+}
+
+// describeToolCall renders a tool call as name(input), shortening long input.
+func describeToolCall(call agent.ToolCall) string {
+	input := []rune(string(call.Input))
+	if len(input) > 80 {
+		input = append(input[:77], []rune("...")...)
 	}
+	return fmt.Sprintf("%s(%s)", call.Name, string(input))
 }
 
 // View renders the main UI, including both panels and their borders.
@@ -0,0 +1,141 @@
+package models
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/glamour"
+	"github.com/charmbracelet/lipgloss"
+	"github.com/charmbracelet/x/ansi"
+)
+
+// defaultMarkdownStyle is used when MainModel.MarkdownStyle is not set.
+const defaultMarkdownStyle = "dark"
+
+// toggleRawMsg switches replies between rendered markdown and raw text.
+type toggleRawMsg struct{}
+
+// renderedMessage caches a message as formatted for the viewport, so that
+// only new or changed messages are rendered again.
+type renderedMessage struct {
+	source string // the message it was formatted from
+	width  int
+	raw    bool
+	text   string
+}
+
+// isMarkdownSender reports whether messages from sender are rendered as
+// markdown: the assistant's replies are, everything else is shown as is.
+func isMarkdownSender(sender string) bool {
+	return sender == "Claude" || sender == "AI"
+}
+
+// renderMarkdown renders content as terminal markdown wrapped to width,
+// with code blocks highlighted. glamour wraps everything it renders, code
+// included, so fenced code blocks are rendered apart without wrapping.
+func (m *chatModel) renderMarkdown(content string, width int) (string, error) {
+	if m.markdown == nil || m.markdownWidth != width {
+		// The renderer wraps at a fixed width, so it is replaced when the
+		// width changes.
+		renderer, err := m.newMarkdownRenderer(width)
+		if err != nil {
+			return "", err
+		}
+		m.markdown, m.markdownWidth = renderer, width
+	}
+	if m.codeMarkdown == nil {
+		renderer, err := m.newMarkdownRenderer(0)
+		if err != nil {
+			return "", err
+		}
+		m.codeMarkdown = renderer
+	}
+
+	var parts []string
+	for _, block := range splitFences(content) {
+		renderer := m.markdown
+		if block.code {
+			renderer = m.codeMarkdown
+		}
+		out, err := renderer.Render(block.text)
+		if err != nil {
+			return "", err
+		}
+		if out = trimBlankLines(out); out != "" {
+			parts = append(parts, out)
+		}
+	}
+	return strings.Join(parts, "\n\n"), nil
+}
+
+// trimBlankLines removes the blank lines, which may hold spaces and escape
+// sequences, that glamour puts around what it renders.
+func trimBlankLines(s string) string {
+	lines := strings.Split(s, "\n")
+	blank := func(line string) bool { return strings.TrimSpace(ansi.Strip(line)) == "" }
+	for len(lines) > 0 && blank(lines[0]) {
+		lines = lines[1:]
+	}
+	for len(lines) > 0 && blank(lines[len(lines)-1]) {
+		lines = lines[:len(lines)-1]
+	}
+	return strings.Join(lines, "\n")
+}
+
+// newMarkdownRenderer returns a renderer in the chat's style wrapping at
+// width, or not at all if width is 0.
+func (m *chatModel) newMarkdownRenderer(width int) (*glamour.TermRenderer, error) {
+	style := m.markdownStyle
+	if style == "" {
+		style = defaultMarkdownStyle
+	}
+	return glamour.NewTermRenderer(
+		glamour.WithStylePath(style),
+		glamour.WithColorProfile(lipgloss.ColorProfile()),
+		glamour.WithWordWrap(width),
+	)
+}
+
+// markdownBlock is a part of a message: a fenced code block or the text
+// between them.
+type markdownBlock struct {
+	text string
+	code bool
+}
+
+// splitFences splits markdown at the fenced code blocks that start a line.
+// Fences nested in lists or quotes stay with the surrounding text.
+func splitFences(content string) []markdownBlock {
+	var blocks []markdownBlock
+	var current strings.Builder
+	fence := "" // the opening fence while in a code block
+	flush := func(code bool) {
+		if current.Len() > 0 {
+			blocks = append(blocks, markdownBlock{text: current.String(), code: code})
+			current.Reset()
+		}
+	}
+	for _, line := range strings.SplitAfter(content, "\n") {
+		trimmed := strings.TrimRight(line, " \t\r\n")
+		switch {
+		case fence == "" && (strings.HasPrefix(trimmed, "```") || strings.HasPrefix(trimmed, "~~~")):
+			flush(false)
+			fence = trimmed[:len(trimmed)-len(strings.TrimLeft(trimmed, trimmed[:1]))]
+			current.WriteString(line)
+		case fence != "" && strings.HasPrefix(trimmed, fence) && strings.Trim(trimmed, fence[:1]) == "":
+			current.WriteString(line)
+			flush(true)
+			fence = ""
+		default:
+			current.WriteString(line)
+		}
+	}
+	// An unclosed fence runs to the end, as in CommonMark.
+	flush(fence != "")
+	return blocks
+}
+
+// setRaw shows replies as raw text instead of rendered markdown, or back.
+func (m *chatModel) setRaw(raw bool) {
+	m.raw = raw
+	m.viewport.SetContent(m.formatMessages())
+}
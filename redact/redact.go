@@ -0,0 +1,204 @@
+// Package redact masks secrets such as API keys and private keys in text
+// before it is logged or sent to the model.
+package redact
+
+import (
+	"fmt"
+	"math"
+	"regexp"
+	"strings"
+)
+
+// detector finds one kind of secret. If the expression has a capturing
+// group, only the first group is masked, so that context such as the name of
+// a variable is kept.
+type detector struct {
+	name string
+	re   *regexp.Regexp
+}
+
+// builtin are the detectors every Redactor applies.
+var builtin = []detector{
+	{"private-key", regexp.MustCompile(`-----BEGIN [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----[\s\S]*?-----END [A-Z0-9 ]*PRIVATE KEY( BLOCK)?-----`)},
+	{"anthropic-key", regexp.MustCompile(`\bsk-ant-[A-Za-z0-9_-]{20,}`)},
+	{"openai-key", regexp.MustCompile(`\bsk-(?:proj-)?[A-Za-z0-9_-]{32,}`)},
+	{"aws-access-key", regexp.MustCompile(`\b(?:AKIA|ASIA|AGPA|AIDA|AROA)[0-9A-Z]{16}\b`)},
+	{"aws-secret-key", regexp.MustCompile(`(?i)aws_?secret_?(?:access_?)?key["']?\s*[:=]\s*["']?([A-Za-z0-9/+]{40})\b`)},
+	{"github-token", regexp.MustCompile(`\b(?:gh[pousr]_[A-Za-z0-9]{36,}|github_pat_[A-Za-z0-9_]{22,})`)},
+	{"gitlab-token", regexp.MustCompile(`\bglpat-[A-Za-z0-9_-]{20,}`)},
+	{"slack-token", regexp.MustCompile(`\bxox[abposr]-[A-Za-z0-9-]{10,}`)},
+	{"google-api-key", regexp.MustCompile(`\bAIza[0-9A-Za-z_-]{35}`)},
+	{"stripe-key", regexp.MustCompile(`\b[rs]k_live_[0-9A-Za-z]{24,}`)},
+	{"jwt", regexp.MustCompile(`\beyJ[A-Za-z0-9_-]{8,}\.eyJ[A-Za-z0-9_-]{8,}\.[A-Za-z0-9_-]{8,}`)},
+	{"url-password", regexp.MustCompile(`[a-z][a-z0-9+.-]*://[^\s:/@"']+:([^\s:/@"']+)@`)},
+}
+
+// Entropy detection looks at runs of at least 24 token characters whose
+// Shannon entropy per character exceeds entropyThreshold. Hex strings such as
+// commit hashes stay below it.
+var tokenRe = regexp.MustCompile(`[A-Za-z0-9+/_=-]{24,}`)
+
+const entropyThreshold = 4.3
+
+// idPrefixes start the identifiers the API assigns to messages, requests
+// and tool calls. They are random but not secret, and logs and replays
+// need them intact.
+var idPrefixes = []string{"msg_", "req_", "toolu_", "srvtoolu_"}
+
+// checksumPrefixes precede base64 checksums, such as the h1: hashes in
+// go.sum and the sha512- integrity hashes of npm lockfiles.
+var checksumPrefixes = []string{"h1:", "sha1-", "sha256-", "sha384-", "sha512-"}
+
+// Options configures a Redactor.
+type Options struct {
+	// Patterns are additional regular expressions of secrets. Only the first
+	// capturing group is masked, if there is one.
+	Patterns []string
+	// DisableEntropy turns off masking of random-looking strings that no
+	// other detector recognises.
+	DisableEntropy bool
+}
+
+// Redactor masks secrets in text. A nil Redactor leaves text unchanged.
+type Redactor struct {
+	detectors []detector
+	entropy   bool
+}
+
+// New returns a Redactor with the built-in detectors and those in opts.
+func New(opts Options) (*Redactor, error) {
+	r := &Redactor{
+		detectors: append([]detector(nil), builtin...),
+		entropy:   !opts.DisableEntropy,
+	}
+	for _, pattern := range opts.Patterns {
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid redaction pattern %q: %v", pattern, err)
+		}
+		r.detectors = append(r.detectors, detector{name: "custom", re: re})
+	}
+	return r, nil
+}
+
+// Default returns a Redactor with only the built-in detectors.
+func Default() *Redactor {
+	r, _ := New(Options{})
+	return r
+}
+
+// Redact returns s with every secret found replaced by [REDACTED:<kind>].
+func (r *Redactor) Redact(s string) string {
+	if r == nil || s == "" {
+		return s
+	}
+	for _, d := range r.detectors {
+		s = mask(s, d)
+	}
+	if r.entropy {
+		s = maskEntropy(s)
+	}
+	return s
+}
+
+// maskEntropy replaces the random-looking tokens in s that are not
+// identifiers or checksums.
+func maskEntropy(s string) string {
+	var b strings.Builder
+	last := 0
+	for _, m := range tokenRe.FindAllStringIndex(s, -1) {
+		token := s[m[0]:m[1]]
+		if !isSecretLike(token) || isIDOrChecksum(token, s[:m[0]]) {
+			continue
+		}
+		b.WriteString(s[last:m[0]])
+		b.WriteString(placeholder("high-entropy"))
+		last = m[1]
+	}
+	if last == 0 {
+		return s
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+// isIDOrChecksum reports whether token, which follows before, is an API
+// identifier or a checksum. Token characters include "-" but not ":", so a
+// checksum prefix is either part of the token or just before it.
+func isIDOrChecksum(token, before string) bool {
+	return hasAnyPrefix(token, idPrefixes) || hasAnyPrefix(token, checksumPrefixes) || hasAnySuffix(before, checksumPrefixes)
+}
+
+func hasAnyPrefix(s string, prefixes []string) bool {
+	for _, prefix := range prefixes {
+		if strings.HasPrefix(s, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+func hasAnySuffix(s string, suffixes []string) bool {
+	for _, suffix := range suffixes {
+		if strings.HasSuffix(s, suffix) {
+			return true
+		}
+	}
+	return false
+}
+
+// mask replaces the matches of d in s, or their first group if d has one.
+func mask(s string, d detector) string {
+	matches := d.re.FindAllStringSubmatchIndex(s, -1)
+	if matches == nil {
+		return s
+	}
+	var b strings.Builder
+	last := 0
+	for _, m := range matches {
+		start, end := m[0], m[1]
+		if len(m) > 2 && m[2] >= 0 {
+			start, end = m[2], m[3]
+		}
+		b.WriteString(s[last:start])
+		b.WriteString(placeholder(d.name))
+		last = end
+	}
+	b.WriteString(s[last:])
+	return b.String()
+}
+
+func placeholder(kind string) string {
+	return "[REDACTED:" + kind + "]"
+}
+
+// isSecretLike reports whether token looks like a random key rather than a
+// word, identifier or path: it mixes letters and digits and has high
+// entropy.
+func isSecretLike(token string) bool {
+	var letters, digits bool
+	for _, c := range token {
+		switch {
+		case c >= '0' && c <= '9':
+			digits = true
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z':
+			letters = true
+		}
+	}
+	return letters && digits && entropy(token) > entropyThreshold
+}
+
+// entropy returns the Shannon entropy of s in bits per character.
+func entropy(s string) float64 {
+	counts := make(map[rune]int)
+	for _, c := range s {
+		counts[c]++
+	}
+	var h float64
+	n := float64(len(s))
+	for _, count := range counts {
+		p := float64(count) / n
+		h -= p * math.Log2(p)
+	}
+	return h
+}
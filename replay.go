@@ -0,0 +1,131 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"strings"
+	"sync"
+
+	"agent/agent"
+	"agent/config"
+	"agent/logger"
+	"agent/models"
+	"agent/replay"
+	"github.com/anthropics/anthropic-sdk-go"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// replayCommand re-runs a recorded session without contacting the model,
+// printing the conversation or showing it in the TUI, and reports where the
+// replay diverged from the recording.
+func replayCommand(cfg *config.Config, args []string) error {
+	// Accept the session before or after the flags.
+	var id string
+	if len(args) > 0 && !strings.HasPrefix(args[0], "-") {
+		id, args = args[0], args[1:]
+	}
+	flags := flag.NewFlagSet("replay", flag.ExitOnError)
+	exec := flags.Bool("exec", false, "run the tools against the current tree instead of using the recorded results, and report differences")
+	tui := flags.Bool("tui", false, "show the replay in the terminal UI")
+	flags.Usage = func() {
+		fmt.Fprintln(flags.Output(), "usage: agent replay [session] [-exec] [-tui]\n\nThe session defaults to the latest before this one; an ID prefix is enough.")
+		flags.PrintDefaults()
+	}
+	flags.Parse(args)
+	if id == "" {
+		id = flags.Arg(0)
+	}
+
+	session, err := findRecordedSession(cfg.Logs.LogDir(), id)
+	if err != nil {
+		return err
+	}
+	recording, err := replay.Load(session)
+	if err != nil {
+		return err
+	}
+	player := replay.NewPlayer(recording)
+	player.Exec = *exec
+
+	// MCP servers are not connected to: without -exec their tools are not
+	// run, and with it their results are reported as divergences.
+	client := anthropic.NewClient()
+	env := setup(cfg, &client, false)
+	defer env.Close()
+	player.Attach(env.agent)
+
+	fmt.Printf("Replaying session %s: %d messages\n", session.ID, len(recording.Inputs))
+	if *tui {
+		m := &models.MainModel{
+			Agent:         env.agent,
+			Replay:        recording.Inputs,
+			MaxInputLines: cfg.UI.InputLines(),
+			MarkdownStyle: markdownStyle(cfg),
+		}
+		if _, err := tea.NewProgram(m, tea.WithAltScreen(), tea.WithMouseCellMotion()).Run(); err != nil {
+			return err
+		}
+	} else if err := replayInputs(env.agent, recording.Inputs); err != nil {
+		fmt.Printf("Replay stopped: %v\n", err)
+	}
+
+	divergences := player.Divergences()
+	if remaining := player.Remaining(); remaining > 0 {
+		fmt.Printf("%d recorded responses were not used.\n", remaining)
+	}
+	if len(divergences) == 0 {
+		fmt.Println("The replay matched the recording.")
+		return nil
+	}
+	fmt.Printf("The replay diverged from the recording %d times:\n", len(divergences))
+	for _, d := range divergences {
+		fmt.Printf("  %s\n", d)
+	}
+	return nil
+}
+
+// findRecordedSession returns the session with the given ID prefix, or the
+// latest one other than the session just started for the replay itself.
+func findRecordedSession(dir, id string) (logger.Session, error) {
+	if id != "" && id != "latest" {
+		return logger.FindSession(dir, id)
+	}
+	sessions, err := logger.ListSessions(dir)
+	if err != nil {
+		return logger.Session{}, err
+	}
+	for i := len(sessions) - 1; i >= 0; i-- {
+		if sessions[i].ID != logger.SessionID() {
+			return sessions[i], nil
+		}
+	}
+	return logger.Session{}, fmt.Errorf("no sessions found in %s", dir)
+}
+
+// replayInputs sends the recorded user messages in turn, printing the
+// conversation.
+func replayInputs(a *agent.Agent, inputs []string) error {
+	var printMu sync.Mutex
+	printEvent := func(event agent.Event) {
+		printMu.Lock()
+		defer printMu.Unlock()
+		switch event.Kind {
+		case agent.EventText:
+			fmt.Printf("\u001b[93mClaude\u001b[0m: %s\n", event.Text)
+		case agent.EventToolStart:
+			fmt.Printf("\u001b[92mtool\u001b[0m: %s(%s)\n", event.Call.Name, event.Call.Input)
+		case agent.EventToolDone:
+			if event.Err != nil {
+				fmt.Printf("\u001b[91mtool failed\u001b[0m: %s: %v\n", event.Call.Name, event.Err)
+			}
+		}
+	}
+	for _, input := range inputs {
+		fmt.Printf("\u001b[94mYou\u001b[0m: %s\n", input)
+		if err := a.Send(context.Background(), input, printEvent); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,226 @@
+// Package replay re-drives the agent from a recorded session: model replies
+// come from the session log instead of the API, and tool results are either
+// taken from the log or produced by running the tools again and compared.
+package replay
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+
+	"agent/agent"
+	"agent/logger"
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// response is a recorded model reply with the digest of its request.
+type response struct {
+	digest  string
+	message json.RawMessage
+}
+
+// toolResult is the recorded outcome of a tool call.
+type toolResult struct {
+	result string
+	err    string
+}
+
+// callKey identifies a tool call by the 1-based number of the model
+// response that requested it and its tool_use ID, which is only unique
+// within a conversation.
+type callKey struct {
+	request int
+	id      string
+}
+
+// Recording holds what a session needs to be replayed.
+type Recording struct {
+	SessionID string
+	// Inputs are the user messages in the order they were sent.
+	Inputs    []string
+	responses []response
+	results   map[callKey]toolResult
+}
+
+// record is the subset of a log line read for a replay.
+type record struct {
+	Event     string          `json:"event"`
+	Text      string          `json:"text"`
+	Digest    string          `json:"messages_sha256"`
+	Message   json.RawMessage `json:"message"`
+	ToolUseID string          `json:"tool_use_id"`
+	Result    string          `json:"result"`
+	Error     string          `json:"error"`
+}
+
+// Load reads the recording of a session from its log files.
+func Load(session logger.Session) (*Recording, error) {
+	rec := &Recording{SessionID: session.ID, results: make(map[callKey]toolResult)}
+	var digest string
+	for _, path := range session.Files {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		scanner := bufio.NewScanner(file)
+		scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+		for scanner.Scan() {
+			var r record
+			if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+				continue
+			}
+			switch r.Event {
+			case logger.EventUserMessage:
+				rec.Inputs = append(rec.Inputs, r.Text)
+			case logger.EventModelRequest:
+				digest = r.Digest
+			case logger.EventModelResponse:
+				if len(r.Message) > 0 {
+					rec.responses = append(rec.responses, response{digest: digest, message: r.Message})
+				}
+				digest = ""
+			case logger.EventToolResult:
+				if strings.Contains(r.ToolUseID, "[REDACTED:") {
+					file.Close()
+					return nil, fmt.Errorf("session %s was logged with redacted tool call IDs, so its tool results cannot be matched to the calls; it cannot be replayed", session.ID)
+				}
+				if r.ToolUseID != "" {
+					rec.results[callKey{request: len(rec.responses), id: r.ToolUseID}] = toolResult{result: r.Result, err: r.Error}
+				}
+			}
+		}
+		err = scanner.Err()
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+	if len(rec.responses) == 0 {
+		return nil, fmt.Errorf("session %s has no recorded model responses; sessions are recorded from this version on, at log level info or lower", session.ID)
+	}
+	return rec, nil
+}
+
+// Divergence is a point where the replay differs from the recording.
+type Divergence struct {
+	// Request is the 1-based number of the model request it was found at.
+	Request int
+	// ToolUseID is set if a tool result differs.
+	ToolUseID string
+	Detail    string
+}
+
+func (d Divergence) String() string {
+	if d.ToolUseID != "" {
+		return fmt.Sprintf("request %d, tool call %s: %s", d.Request, d.ToolUseID, d.Detail)
+	}
+	return fmt.Sprintf("request %d: %s", d.Request, d.Detail)
+}
+
+// Player serves a recording to an agent. It is the agent's Provider, and
+// its ToolHook returns or compares the recorded tool results.
+type Player struct {
+	rec *Recording
+	// Exec runs the tools instead of returning their recorded results, and
+	// reports results that differ.
+	Exec bool
+
+	mu          sync.Mutex
+	next        int
+	divergences []Divergence
+}
+
+// NewPlayer returns a Player for rec.
+func NewPlayer(rec *Recording) *Player {
+	return &Player{rec: rec}
+}
+
+// Attach makes a play back the recording.
+func (p *Player) Attach(a *agent.Agent) {
+	a.Provider = p
+	a.ToolHook = p.toolHook
+}
+
+// ErrExhausted is returned when the agent makes more model requests than
+// were recorded.
+var ErrExhausted = errors.New("the recording has no more model responses")
+
+// NewMessage returns the next recorded response, noting a divergence if the
+// conversation sent differs from the one recorded.
+func (p *Player) NewMessage(ctx context.Context, params anthropic.MessageNewParams) (*anthropic.Message, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.next >= len(p.rec.responses) {
+		return nil, ErrExhausted
+	}
+	resp := p.rec.responses[p.next]
+	p.next++
+	if resp.digest != "" && resp.digest != agent.MessagesDigest(params.Messages) {
+		p.divergences = append(p.divergences, Divergence{Request: p.next, Detail: "the conversation sent differs from the recorded one"})
+	}
+
+	var message anthropic.Message
+	if err := json.Unmarshal(resp.message, &message); err != nil {
+		return nil, fmt.Errorf("failed to decode recorded response %d: %w", p.next, err)
+	}
+	return &message, nil
+}
+
+// toolHook returns the recorded result of a tool call, or with Exec runs the
+// tool and compares its result with the recorded one.
+func (p *Player) toolHook(call agent.ToolCall, run func() (string, error)) (string, error) {
+	p.mu.Lock()
+	request := p.next
+	recorded, ok := p.rec.results[callKey{request: request, id: call.ID}]
+	p.mu.Unlock()
+
+	if !p.Exec {
+		if !ok {
+			return "", fmt.Errorf("no result was recorded for tool call %s", call.ID)
+		}
+		if recorded.err != "" {
+			return "", errors.New(recorded.err)
+		}
+		return recorded.result, nil
+	}
+
+	result, err := run()
+	var detail string
+	switch {
+	case !ok:
+		detail = "no result was recorded"
+	case err != nil && recorded.err == "":
+		detail = fmt.Sprintf("%s now fails: %v", call.Name, err)
+	case err == nil && recorded.err != "":
+		detail = fmt.Sprintf("%s now succeeds; it failed with: %s", call.Name, recorded.err)
+	case err != nil && err.Error() != recorded.err:
+		detail = fmt.Sprintf("%s fails differently: %v", call.Name, err)
+	case err == nil && result != recorded.result:
+		detail = fmt.Sprintf("%s returns a different result (%d bytes, recorded %d)", call.Name, len(result), len(recorded.result))
+	}
+	if detail != "" {
+		p.mu.Lock()
+		p.divergences = append(p.divergences, Divergence{Request: request, ToolUseID: call.ID, Detail: detail})
+		p.mu.Unlock()
+	}
+	return result, err
+}
+
+// Divergences returns the differences found so far.
+func (p *Player) Divergences() []Divergence {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]Divergence(nil), p.divergences...)
+}
+
+// Remaining returns the number of recorded responses not yet played.
+func (p *Player) Remaining() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return len(p.rec.responses) - p.next
+}
@@ -0,0 +1,187 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"agent/agent"
+	"agent/logger"
+	"agent/tools"
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// Tool call IDs as the API assigns them: random enough to look like
+// secrets to an entropy detector.
+const (
+	firstID  = "toolu_01Xk9pQz7RvW3mNb8Lc2Ht5Y"
+	secondID = "toolu_01Qm4Tz8Kd2Wn6Rb9Xc3Lp7V"
+)
+
+// script is the model side of the recorded session: two lookups at once,
+// one of which fails, then an answer.
+var script = []string{
+	`{"id": "msg_01Hq7Rz3Kt9Wm2Xb6Lc8Np4V", "type": "message", "role": "assistant", "model": "claude-3-7-sonnet-latest",
+	  "stop_reason": "tool_use", "usage": {"input_tokens": 10, "output_tokens": 5},
+	  "content": [{"type": "tool_use", "id": "` + firstID + `", "name": "lookup", "input": {"key": "a"}},
+	              {"type": "tool_use", "id": "` + secondID + `", "name": "lookup", "input": {"key": "missing"}}]}`,
+	`{"id": "msg_01Tb5Wk2Rx8Qm4Zn7Lc3Hp9V", "type": "message", "role": "assistant", "model": "claude-3-7-sonnet-latest",
+	  "stop_reason": "end_turn", "usage": {"input_tokens": 20, "output_tokens": 3},
+	  "content": [{"type": "text", "text": "a is 1."}]}`,
+}
+
+type scriptedProvider struct {
+	mu        sync.Mutex
+	responses []string
+}
+
+func (p *scriptedProvider) NewMessage(ctx context.Context, params anthropic.MessageNewParams) (*anthropic.Message, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	var message anthropic.Message
+	err := json.Unmarshal([]byte(p.responses[0]), &message)
+	p.responses = p.responses[1:]
+	return &message, err
+}
+
+type lookupInput struct {
+	Key string `json:"key"`
+}
+
+// lookupTool returns a read-only tool, so that both calls run at once,
+// answering from values.
+func lookupTool(values map[string]string) tools.ToolDefinition {
+	return tools.ToolDefinition{
+		Name:        "lookup",
+		Description: "Look up a key.",
+		InputSchema: tools.GenerateSchema[lookupInput](),
+		Function: func(input json.RawMessage) (string, error) {
+			var in lookupInput
+			if err := json.Unmarshal(input, &in); err != nil {
+				return "", err
+			}
+			if value, ok := values[in.Key]; ok {
+				return value, nil
+			}
+			return "", errors.New("no such key: " + in.Key)
+		},
+		ReadOnly: true,
+	}
+}
+
+// results runs input on a and returns the tool results and errors by
+// tool_use ID.
+func results(t *testing.T, a *agent.Agent, input string) map[string]string {
+	t.Helper()
+	var mu sync.Mutex
+	got := make(map[string]string)
+	err := a.Send(context.Background(), input, func(event agent.Event) {
+		if event.Kind != agent.EventToolDone {
+			return
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		if event.Err != nil {
+			got[event.Call.ID] = "error: " + event.Err.Error()
+		} else {
+			got[event.Call.ID] = event.Result
+		}
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return got
+}
+
+// recordSession runs the script with the session logged to dir and returns
+// the tool results.
+func recordSession(t *testing.T, dir string) map[string]string {
+	t.Helper()
+	if err := logger.Initialize(logger.Options{Dir: dir}); err != nil {
+		t.Fatal(err)
+	}
+	defer logger.Close()
+	a := agent.NewAgent(nil, nil, []tools.ToolDefinition{lookupTool(map[string]string{"a": "1"})})
+	a.Provider = &scriptedProvider{responses: script}
+	return results(t, a, "What is a?")
+}
+
+func TestReplay(t *testing.T) {
+	dir := t.TempDir()
+	recorded := recordSession(t, dir)
+	if len(recorded) != 2 {
+		t.Fatalf("recorded %d tool results, want 2", len(recorded))
+	}
+
+	session, err := logger.FindSession(dir, "latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rec, err := Load(session)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(rec.Inputs) != 1 || rec.Inputs[0] != "What is a?" {
+		t.Errorf("Inputs = %q", rec.Inputs)
+	}
+
+	t.Run("recorded results", func(t *testing.T) {
+		a := agent.NewAgent(nil, nil, []tools.ToolDefinition{lookupTool(nil)})
+		player := NewPlayer(rec)
+		player.Attach(a)
+		got := results(t, a, rec.Inputs[0])
+		for id, want := range recorded {
+			if got[id] != want {
+				t.Errorf("result of %s = %q, want %q", id, got[id], want)
+			}
+		}
+		if d := player.Divergences(); len(d) != 0 {
+			t.Errorf("divergences: %v", d)
+		}
+		if n := player.Remaining(); n != 0 {
+			t.Errorf("%d responses remain", n)
+		}
+	})
+
+	t.Run("exec", func(t *testing.T) {
+		a := agent.NewAgent(nil, nil, []tools.ToolDefinition{lookupTool(map[string]string{"a": "2"})})
+		player := NewPlayer(rec)
+		player.Exec = true
+		player.Attach(a)
+		results(t, a, rec.Inputs[0])
+		var calls []string
+		for _, d := range player.Divergences() {
+			if d.ToolUseID != "" {
+				calls = append(calls, d.ToolUseID)
+			}
+		}
+		// The second request differs too, since it carries the new result.
+		if len(calls) != 1 || calls[0] != firstID {
+			t.Errorf("divergences = %v, want one for %s", player.Divergences(), firstID)
+		}
+	})
+}
+
+func TestLoadRefusesRedactedIDs(t *testing.T) {
+	dir := t.TempDir()
+	log := strings.Join([]string{
+		`{"event":"user_message","text":"hi"}`,
+		`{"event":"model_response","message":{"role":"assistant","content":[{"type":"text","text":"hello"}]}}`,
+		`{"event":"tool_result","tool_use_id":"[REDACTED:high-entropy]","result":"x"}`,
+	}, "\n")
+	if err := os.WriteFile(filepath.Join(dir, "session_20250102-150405-1a2b3c.jsonl"), []byte(log), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	session, err := logger.FindSession(dir, "latest")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := Load(session); err == nil || !strings.Contains(err.Error(), "redacted") {
+		t.Errorf("Load: err = %v, want an error about redacted IDs", err)
+	}
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path/filepath"
+	"time"
+
+	"agent/agent"
+	"agent/config"
+	"agent/logger"
+	"agent/lsp"
+	"agent/mcp"
+	"agent/redact"
+	"agent/tools"
+	"agent/tracing"
+	"github.com/anthropics/anthropic-sdk-go"
+	"github.com/charmbracelet/lipgloss"
+)
+
+// environment is an agent wired to its tools and the services behind them.
+type environment struct {
+	agent  *agent.Agent
+	mcp    *mcp.Manager // nil if no MCP servers are used
+	closer []func()
+}
+
+// setup configures the tools package from cfg, starts the configured
+// services and builds an agent with every available tool. MCP servers are
+// only connected to if connectMCP is set.
+func setup(cfg *config.Config, client *anthropic.Client, connectMCP bool) *environment {
+	env := &environment{}
+
+	tools.SetPermissionGate(cfg.Permissions.Check)
+	if !cfg.DisableGoFormat {
+		tools.RegisterPostWriteHook(tools.GoImportsHook)
+	}
+	for _, f := range cfg.Formatters {
+		tools.RegisterPostWriteHook(tools.ExternalFormatterHook(f.Extensions, f.Command))
+	}
+
+	registry := tools.NewRegistry(tools.BuiltinDefinitions()...)
+	var languageServers *lsp.Manager
+	if len(cfg.LanguageServers) > 0 {
+		languageServers = lsp.NewManager(tools.WorkspaceRoot(), cfg.LanguageServers)
+		env.closer = append(env.closer, languageServers.Close)
+		for _, def := range languageServers.ToolDefinitions() {
+			if err := registry.Register(def); err != nil {
+				log.Fatal(err)
+			}
+		}
+	}
+
+	if connectMCP && len(cfg.MCPServers) > 0 {
+		env.mcp = mcp.NewManager(tools.WorkspaceRoot(), cfg.MCPServers)
+		env.closer = append(env.closer, env.mcp.Close)
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		if err := env.mcp.Connect(ctx); err != nil {
+			logger.Error("failed to connect to MCP servers", err)
+		}
+		defs, err := env.mcp.ToolDefinitions(ctx)
+		cancel()
+		if err != nil {
+			logger.Error("failed to load MCP tools", err)
+		}
+		for _, def := range defs {
+			if err := registry.Register(def); err != nil {
+				logger.Error("failed to register MCP tool", err)
+			}
+		}
+	}
+
+	for _, dir := range cfg.ToolDirs() {
+		if err := registry.LoadDir(dir); err != nil {
+			logger.Error("failed to load external tools", err, "dir", dir)
+		}
+	}
+
+	env.agent = agent.NewAgent(client, nil, registry.Definitions())
+	env.agent.AutoGoCheck = !cfg.DisableAutoGoCheck
+	env.agent.MaxResultBytes = cfg.ToolResults.MaxBytes
+	env.agent.ToolResultLimits = cfg.ToolResults.PerTool
+	if cfg.Redaction.ToolResults {
+		env.agent.Redactor = redactor(cfg)
+	}
+	env.closer = append(env.closer, func() { env.agent.Close() })
+	if languageServers != nil {
+		env.agent.PostEditHooks = append(env.agent.PostEditHooks, languageServers.PostEditFeedback)
+	}
+	return env
+}
+
+// redactor returns the Redactor configured by cfg.
+func redactor(cfg *config.Config) *redact.Redactor {
+	r, err := redact.New(redact.Options{
+		Patterns:       cfg.Redaction.Patterns,
+		DisableEntropy: cfg.Redaction.DisableEntropy,
+	})
+	if err != nil {
+		log.Fatal(err)
+	}
+	return r
+}
+
+// markdownStyle returns the style replies are rendered with: the configured
+// one, or the dark or light style to suit the terminal. It must be called
+// before the TUI starts, as it may query the terminal.
+func markdownStyle(cfg *config.Config) string {
+	if cfg.UI.MarkdownStyle != "" {
+		return cfg.UI.MarkdownStyle
+	}
+	if lipgloss.HasDarkBackground() {
+		return "dark"
+	}
+	return "light"
+}
+
+// setupTracing installs the trace exporters configured by cfg and returns a
+// function that flushes them.
+func setupTracing(cfg *config.Config) func() {
+	var exporters []tracing.Exporter
+	if cfg.Tracing.Enabled {
+		path := filepath.Join(cfg.Tracing.TraceDir(), fmt.Sprintf("trace_%s.json", logger.SessionID()))
+		file, err := tracing.NewFileExporter(path)
+		if err != nil {
+			log.Fatal(err)
+		}
+		exporters = append(exporters, file)
+	}
+	if endpoint := cfg.Tracing.Endpoint(); endpoint != "" {
+		otlp := tracing.NewOTLPExporter(endpoint, cfg.Tracing.OTLPHeaders, config.AppName)
+		otlp.OnError = func(err error) { logger.Error("failed to export spans", err) }
+		exporters = append(exporters, otlp)
+	}
+	tracing.SetExporters(exporters...)
+	return func() {
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err := tracing.Shutdown(ctx); err != nil {
+			logger.Error("failed to flush spans", err)
+		}
+	}
+}
+
+// Close stops the services started by setup.
+func (e *environment) Close() {
+	for _, close := range e.closer {
+		close()
+	}
+}
@@ -0,0 +1,422 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+const devNull = "/dev/null"
+
+type ApplyPatchInput struct {
+	Patch string `json:"patch" jsonschema_description:"A unified diff (as produced by 'diff -u' or 'git diff') touching one or more files."`
+}
+
+// patchHunk is a single @@ section of a unified diff.
+type patchHunk struct {
+	header   string
+	oldStart int
+	lines    []diffOp
+}
+
+// patchFile holds the hunks that apply to one file.
+type patchFile struct {
+	oldPath string
+	newPath string
+	hunks   []patchHunk
+}
+
+var hunkHeaderRe = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@`)
+
+// parsePatchPath strips the a/ or b/ prefix and any trailing timestamp from a
+// ---/+++ header line.
+func parsePatchPath(line string) string {
+	p := strings.TrimSpace(line[4:])
+	if i := strings.IndexByte(p, '\t'); i >= 0 {
+		p = p[:i]
+	}
+	if p == devNull {
+		return p
+	}
+	if strings.HasPrefix(p, "a/") || strings.HasPrefix(p, "b/") {
+		p = p[2:]
+	}
+	return p
+}
+
+// hunkCount parses the line count of a hunk header range, which is 1 when
+// omitted.
+func hunkCount(s string) int {
+	if s == "" {
+		return 1
+	}
+	n, _ := strconv.Atoi(s)
+	return n
+}
+
+// isHunkLine reports whether lines[i] continues a hunk rather than starting
+// the next file.
+func isHunkLine(lines []string, i int) bool {
+	line := lines[i]
+	if strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ ") {
+		return false
+	}
+	return line != "" && (line[0] == ' ' || line[0] == '-' || line[0] == '+')
+}
+
+// parsePatch splits a unified diff into per-file hunks.
+func parsePatch(patch string) ([]*patchFile, error) {
+	lines := strings.Split(strings.TrimSuffix(strings.ReplaceAll(patch, "\r\n", "\n"), "\n"), "\n")
+	var files []*patchFile
+	var current *patchFile
+
+	for i := 0; i < len(lines); i++ {
+		line := lines[i]
+		switch {
+		case strings.HasPrefix(line, "--- ") && i+1 < len(lines) && strings.HasPrefix(lines[i+1], "+++ "):
+			current = &patchFile{
+				oldPath: parsePatchPath(line),
+				newPath: parsePatchPath(lines[i+1]),
+			}
+			files = append(files, current)
+			i++
+		case strings.HasPrefix(line, "@@"):
+			if current == nil {
+				return nil, fmt.Errorf("line %d: hunk header before any ---/+++ file header", i+1)
+			}
+			m := hunkHeaderRe.FindStringSubmatch(line)
+			if m == nil {
+				return nil, fmt.Errorf("line %d: malformed hunk header %q", i+1, line)
+			}
+			start := i + 1
+			oldStart, _ := strconv.Atoi(m[1])
+			oldCount, newCount := hunkCount(m[2]), hunkCount(m[4])
+			hunk := patchHunk{header: line, oldStart: oldStart}
+			// The counts in the header say where the hunk ends, so that a
+			// blank context line is not mistaken for the end of the hunk.
+			oldSeen, newSeen := 0, 0
+			for i+1 < len(lines) && (oldSeen < oldCount || newSeen < newCount) {
+				next := lines[i+1]
+				op := diffOp{kind: ' '}
+				if next != "" {
+					if next[0] == '\\' {
+						i++
+						continue
+					}
+					if next[0] != ' ' && next[0] != '-' && next[0] != '+' {
+						break
+					}
+					op = diffOp{kind: next[0], text: next[1:]}
+				}
+				if op.kind != '+' {
+					oldSeen++
+				}
+				if op.kind != '-' {
+					newSeen++
+				}
+				hunk.lines = append(hunk.lines, op)
+				i++
+			}
+			if i+1 < len(lines) && strings.HasPrefix(lines[i+1], "\\") {
+				i++
+			}
+			if oldSeen != oldCount || newSeen != newCount {
+				return nil, fmt.Errorf("line %d: hunk has %d old and %d new lines, but its header %q says %d and %d",
+					start, oldSeen, newSeen, line, oldCount, newCount)
+			}
+			if i+1 < len(lines) && isHunkLine(lines, i+1) {
+				return nil, fmt.Errorf("line %d: hunk has more lines than its header %q says", start, line)
+			}
+			current.hunks = append(current.hunks, hunk)
+		}
+	}
+
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no file headers (---/+++) found in patch")
+	}
+	return files, nil
+}
+
+// findHunk locates old within lines, preferring the position closest to want
+// and never matching before min.
+func findHunk(lines, old []string, want, min int) int {
+	matches := func(pos int) bool {
+		if pos < min || pos+len(old) > len(lines) {
+			return false
+		}
+		for i := range old {
+			if lines[pos+i] != old[i] {
+				return false
+			}
+		}
+		return true
+	}
+	if want < min {
+		want = min
+	}
+	for delta := 0; want-delta >= min || want+delta <= len(lines); delta++ {
+		if matches(want + delta) {
+			return want + delta
+		}
+		if delta > 0 && matches(want-delta) {
+			return want - delta
+		}
+	}
+	return -1
+}
+
+// applyHunks applies every hunk to content and returns the new content along
+// with a description of each hunk that failed to apply.
+func applyHunks(content string, hunks []patchHunk) (string, []string) {
+	lines := splitLines(content)
+	var result []string
+	var failures []string
+	pos, offset := 0, 0
+
+	for i, hunk := range hunks {
+		var old, replacement []string
+		for _, op := range hunk.lines {
+			if op.kind != '+' {
+				old = append(old, op.text)
+			}
+			if op.kind != '-' {
+				replacement = append(replacement, op.text)
+			}
+		}
+
+		// A pure insertion's start line is the line it follows, not replaces.
+		want := hunk.oldStart - 1 + offset
+		if len(old) == 0 {
+			want = hunk.oldStart + offset
+		}
+		at := findHunk(lines, old, want, pos)
+		if at < 0 {
+			failures = append(failures, fmt.Sprintf("hunk %d (%s): context does not match the current file content", i+1, hunk.header))
+			continue
+		}
+
+		result = append(result, lines[pos:at]...)
+		result = append(result, replacement...)
+		pos = at + len(old)
+		offset += len(replacement) - len(old)
+	}
+	result = append(result, lines[pos:]...)
+
+	if len(result) == 0 {
+		return "", failures
+	}
+	return strings.Join(result, "\n") + "\n", failures
+}
+
+// removePatchedFile deletes a file removed or renamed by a patch, keeping a
+// checkpoint so the deletion can be undone.
+func removePatchedFile(path string) error {
+	if _, _, err := resolveInWorkspace(path); err != nil {
+		return err
+	}
+	if err := checkPermission("delete", path); err != nil {
+		return err
+	}
+	restore, err := backupFile(path)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil {
+		return err
+	}
+	tracker.forget(path)
+	noteChanged(path)
+	pushCheckpoint("delete "+path, restore)
+	return nil
+}
+
+func ApplyPatch(input json.RawMessage) (string, error) {
+	applyPatchInput := ApplyPatchInput{}
+	err := json.Unmarshal(input, &applyPatchInput)
+	if err != nil {
+		return "", err
+	}
+
+	if strings.TrimSpace(applyPatchInput.Patch) == "" {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+
+	files, err := parsePatch(applyPatchInput.Patch)
+	if err != nil {
+		return "", err
+	}
+
+	type pendingWrite struct {
+		file       *patchFile
+		original   []byte // what is on disk at oldPath
+		oldContent string
+		newContent string
+	}
+	var pending []pendingWrite
+	var failures []string
+	seen := make(map[string]bool)
+
+	// Every check is made before anything is changed, so that a refused
+	// file leaves the others untouched.
+	for _, file := range files {
+		refused := false
+		paths := []string{file.oldPath, file.newPath}
+		if file.oldPath == file.newPath {
+			paths = paths[:1]
+		}
+		for _, path := range paths {
+			if path == devNull {
+				continue
+			}
+			_, rel, err := resolveInWorkspace(path)
+			if err != nil {
+				failures = append(failures, err.Error())
+				refused = true
+				continue
+			}
+			if seen[rel] {
+				failures = append(failures, fmt.Sprintf("%s: the patch changes the file more than once; merge its hunks into one section", path))
+				refused = true
+			}
+			seen[rel] = true
+		}
+		if refused {
+			continue
+		}
+		if err := checkPatchPermissions(file); err != nil {
+			failures = append(failures, err.Error())
+			continue
+		}
+
+		var original []byte
+		if file.oldPath != devNull {
+			content, err := os.ReadFile(file.oldPath)
+			if err != nil {
+				failures = append(failures, fmt.Sprintf("%s: %v", file.oldPath, err))
+				continue
+			}
+			if err := tracker.checkFresh(file.oldPath, content); err != nil {
+				failures = append(failures, err.Error())
+				continue
+			}
+			original = content
+		}
+		if file.newPath != devNull && file.newPath != file.oldPath {
+			if _, err := os.Lstat(file.newPath); err == nil {
+				failures = append(failures, fmt.Sprintf("%s: patch creates the file but it already exists", file.newPath))
+				continue
+			}
+		}
+
+		oldContent := normalizeNewlines(string(original))
+		newContent, hunkFailures := applyHunks(oldContent, file.hunks)
+		name := file.newPath
+		if name == devNull {
+			name = file.oldPath
+		}
+		for _, f := range hunkFailures {
+			failures = append(failures, fmt.Sprintf("%s: %s", name, f))
+		}
+		if len(hunkFailures) == 0 {
+			pending = append(pending, pendingWrite{file: file, original: original, oldContent: oldContent, newContent: newContent})
+		}
+	}
+
+	if len(failures) > 0 {
+		return "", fmt.Errorf("patch was not applied, no files were changed:\n%s", strings.Join(failures, "\n"))
+	}
+
+	// Each change pushes a checkpoint; if one fails, the earlier ones are
+	// undone so that the patch applies entirely or not at all.
+	changes := 0
+	rollback := func(err error) (string, error) {
+		if undoErr := undoChanges(changes); undoErr != nil {
+			return "", fmt.Errorf("patch failed part way: %w; undoing the files already changed also failed: %v", err, undoErr)
+		}
+		// The files are back as the agent last read them.
+		for _, w := range pending {
+			if w.file.oldPath != devNull {
+				tracker.record(w.file.oldPath, w.original)
+			}
+		}
+		return "", fmt.Errorf("patch was not applied, no files were changed: %w", err)
+	}
+
+	var results []string
+	for _, w := range pending {
+		if w.file.newPath == devNull {
+			if err := removePatchedFile(w.file.oldPath); err != nil {
+				return rollback(err)
+			}
+			changes++
+			results = append(results, fmt.Sprintf("Deleted %s", w.file.oldPath))
+			continue
+		}
+
+		dir := filepath.Dir(w.file.newPath)
+		if createdDir := firstMissingDir(dir); createdDir != "" {
+			if err := os.MkdirAll(dir, 0755); err != nil {
+				return rollback(fmt.Errorf("failed to create directory: %w", err))
+			}
+			pushCheckpoint("create directory "+dir, func() error {
+				return removeEmptyDirs(dir, createdDir)
+			})
+			changes++
+		}
+		written, note, err := writeFile(w.file.newPath, w.newContent)
+		if err != nil {
+			return rollback(err)
+		}
+		changes++
+		if w.file.oldPath != devNull && w.file.oldPath != w.file.newPath {
+			if err := removePatchedFile(w.file.oldPath); err != nil {
+				return rollback(err)
+			}
+			changes++
+		}
+		results = append(results, diffResult(w.file.newPath, w.oldContent, written)+note)
+	}
+
+	return strings.Join(results, "\n"), nil
+}
+
+// checkPatchPermissions consults the permission gate for every change a
+// patch makes to file.
+func checkPatchPermissions(file *patchFile) error {
+	if file.newPath != devNull {
+		path := file.newPath
+		if resolved, err := filepath.EvalSymlinks(path); err == nil {
+			path = resolved
+		}
+		if err := checkPermission("write", path); err != nil {
+			return err
+		}
+		if dir := firstMissingDir(filepath.Dir(file.newPath)); dir != "" {
+			if err := checkPermission("mkdir", dir); err != nil {
+				return err
+			}
+		}
+	}
+	if file.oldPath != devNull && file.oldPath != file.newPath {
+		if err := checkPermission("delete", file.oldPath); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var ApplyPatchDefinition = ToolDefinition{
+	Name: "apply_patch",
+	Description: `Apply a unified diff to one or more files.
+The patch must use the standard unified format with '--- a/path' and '+++ b/path' file headers followed by '@@ -l,s +l,s @@' hunks.
+Use '--- /dev/null' to create a file and '+++ /dev/null' to delete one.
+Existing files touched by the patch must have been read with read_file first, and each file may appear in only one section. Every hunk, path and permission is checked before anything is written, and the hunk line counts must match their headers. If any check fails, nothing is changed and the failures are reported; a write that fails part way is rolled back.
+Returns a unified diff of each resulting change.
+`,
+	InputSchema: GenerateSchema[ApplyPatchInput](),
+	Function:    ApplyPatch,
+}
@@ -0,0 +1,325 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// patchWorkspace makes a temporary workspace the current directory, creates
+// files in it and records them as read.
+func patchWorkspace(t *testing.T, files map[string]string) {
+	t.Helper()
+	dir := t.TempDir()
+	t.Chdir(dir)
+	if err := SetWorkspaceRoot(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() {
+		workspaceMu.Lock()
+		workspaceRoot = ""
+		workspaceMu.Unlock()
+		SetPermissionGate(nil)
+		ResetFileTracker()
+		ResetCheckpoints()
+	})
+	for name, content := range files {
+		if err := os.WriteFile(name, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+		tracker.record(name, []byte(content))
+	}
+}
+
+// checkFiles fails unless each file has the given content, or is missing
+// when the content is "".
+func checkFiles(t *testing.T, want map[string]string) {
+	t.Helper()
+	for name, content := range want {
+		got, err := os.ReadFile(name)
+		if content == "" {
+			if err == nil {
+				t.Errorf("%s exists, want it missing", name)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+		} else if string(got) != content {
+			t.Errorf("%s = %q, want %q", name, got, content)
+		}
+	}
+}
+
+func applyPatch(patch string) (string, error) {
+	input, _ := json.Marshal(ApplyPatchInput{Patch: patch})
+	return ApplyPatch(input)
+}
+
+func TestParsePatch(t *testing.T) {
+	patch := `diff --git a/a.txt b/a.txt
+--- a/a.txt
++++ b/a.txt
+@@ -1,4 +1,4 @@
+ one
+
+-three
++THREE
+ four
+@@ -9 +9 @@
+-nine
++NINE
+
+--- /dev/null
++++ b/new.txt
+@@ -0,0 +1,2 @@
++hello
++world
+\ No newline at end of file
+`
+	files, err := parsePatch(patch)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(files) != 2 {
+		t.Fatalf("got %d files, want 2", len(files))
+	}
+	a, created := files[0], files[1]
+	if a.oldPath != "a.txt" || a.newPath != "a.txt" || len(a.hunks) != 2 {
+		t.Errorf("first file = %+v", a)
+	}
+	// The blank line is context inside the first hunk, not its end.
+	if h := a.hunks[0]; h.oldStart != 1 || len(h.lines) != 5 || h.lines[1] != (diffOp{kind: ' '}) {
+		t.Errorf("first hunk = %+v", h)
+	}
+	if h := a.hunks[1]; h.oldStart != 9 || len(h.lines) != 2 {
+		t.Errorf("second hunk = %+v", h)
+	}
+	if created.oldPath != devNull || created.newPath != "new.txt" || len(created.hunks) != 1 || len(created.hunks[0].lines) != 2 {
+		t.Errorf("second file = %+v", created)
+	}
+}
+
+func TestParsePatchErrors(t *testing.T) {
+	tests := []struct {
+		name, patch, want string
+	}{
+		{"no files", "just some text\n", "no file headers"},
+		{"hunk before file", "@@ -1 +1 @@\n-a\n+b\n", "before any ---/+++"},
+		{"malformed header", "--- a/x\n+++ b/x\n@@ -1,a +1 @@\n-a\n+b\n", "malformed hunk header"},
+		{"too few lines", "--- a/x\n+++ b/x\n@@ -1,3 +1,3 @@\n a\n-b\n+c\n", "header \"@@ -1,3 +1,3 @@\" says 3 and 3"},
+		{"too many lines", "--- a/x\n+++ b/x\n@@ -1 +1 @@\n-a\n+b\n+c\n", "more lines than its header"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parsePatch(tt.patch)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("err = %v, want one containing %q", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyHunks(t *testing.T) {
+	content := "a\nb\nc\nd\ne\n"
+	tests := []struct {
+		name     string
+		hunks    []patchHunk
+		want     string
+		failures int
+	}{
+		{
+			name:  "replace",
+			hunks: []patchHunk{{oldStart: 2, lines: []diffOp{{' ', "b"}, {'-', "c"}, {'+', "C"}}}},
+			want:  "a\nb\nC\nd\ne\n",
+		},
+		{
+			name:  "wrong line number",
+			hunks: []patchHunk{{oldStart: 1, lines: []diffOp{{'-', "d"}, {'+', "D"}}}},
+			want:  "a\nb\nc\nD\ne\n",
+		},
+		{
+			name:  "insertion",
+			hunks: []patchHunk{{oldStart: 0, lines: []diffOp{{'+', "start"}}}},
+			want:  "start\na\nb\nc\nd\ne\n",
+		},
+		{
+			name: "offset carried between hunks",
+			hunks: []patchHunk{
+				{oldStart: 1, lines: []diffOp{{'-', "a"}, {'+', "a1"}, {'+', "a2"}}},
+				{oldStart: 5, lines: []diffOp{{'-', "e"}}},
+			},
+			want: "a1\na2\nb\nc\nd\n",
+		},
+		{
+			name: "context mismatch",
+			hunks: []patchHunk{
+				{header: "@@ -1 +1 @@", oldStart: 1, lines: []diffOp{{'-', "x"}, {'+', "y"}}},
+				{oldStart: 3, lines: []diffOp{{'-', "c"}}},
+			},
+			want:     "a\nb\nd\ne\n",
+			failures: 1,
+		},
+		{
+			name:  "delete everything",
+			hunks: []patchHunk{{oldStart: 1, lines: []diffOp{{'-', "a"}, {'-', "b"}, {'-', "c"}, {'-', "d"}, {'-', "e"}}}},
+			want:  "",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, failures := applyHunks(content, tt.hunks)
+			if len(failures) != tt.failures {
+				t.Errorf("failures = %q, want %d", failures, tt.failures)
+			}
+			if got != tt.want {
+				t.Errorf("content = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestApplyPatch(t *testing.T) {
+	patchWorkspace(t, map[string]string{"a.txt": "one\ntwo\n", "old.txt": "gone\n"})
+	_, err := applyPatch(`--- a/a.txt
++++ b/a.txt
+@@ -1,2 +1,2 @@
+ one
+-two
++TWO
+--- /dev/null
++++ b/dir/new.txt
+@@ -0,0 +1 @@
++new
+--- a/old.txt
++++ /dev/null
+@@ -1 +0,0 @@
+-gone
+`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkFiles(t, map[string]string{"a.txt": "one\nTWO\n", "dir/new.txt": "new\n", "old.txt": ""})
+}
+
+func TestApplyPatchAllOrNothing(t *testing.T) {
+	original := map[string]string{"a.txt": "one\ntwo\n", "b.txt": "three\n"}
+	// Changes a.txt, then b.txt, then creates c/new.txt.
+	patch := `--- a/a.txt
++++ b/a.txt
+@@ -1,2 +1,2 @@
+ one
+-two
++TWO
+--- a/b.txt
++++ b/b.txt
+@@ -1 +1 @@
+-three
++THREE
+--- /dev/null
++++ b/c/new.txt
+@@ -0,0 +1 @@
++new
+`
+	unchanged := map[string]string{"a.txt": "one\ntwo\n", "b.txt": "three\n", "c/new.txt": ""}
+
+	tests := []struct {
+		name  string
+		patch string
+		setup func(t *testing.T)
+		want  string
+		files map[string]string // the files afterwards, if not unchanged
+	}{
+		{
+			name: "duplicate path",
+			patch: patch + `--- a/a.txt
++++ b/a.txt
+@@ -1 +1 @@
+-one
++ONE
+`,
+			want: "more than once",
+		},
+		{
+			name:  "stale file",
+			patch: patch,
+			setup: func(t *testing.T) {
+				if err := os.WriteFile("b.txt", []byte("three\n3\n"), 0644); err != nil {
+					t.Fatal(err)
+				}
+			},
+			want:  "modified on disk",
+			files: map[string]string{"a.txt": "one\ntwo\n", "b.txt": "three\n3\n", "c/new.txt": ""},
+		},
+		{
+			name:  "outside the workspace",
+			patch: patch + "--- /dev/null\n+++ b/../escape.txt\n@@ -0,0 +1 @@\n+x\n",
+			want:  "outside the workspace",
+		},
+		{
+			name:  "permission denied",
+			patch: patch,
+			setup: func(t *testing.T) {
+				SetPermissionGate(func(action, path string) error {
+					if path == filepath.Join("c", "new.txt") {
+						return errors.New("writing c/new.txt is not allowed")
+					}
+					return nil
+				})
+			},
+			want: "not allowed",
+		},
+		{
+			// The gate allows the checks but refuses b.txt when it is
+			// written, after a.txt has been, which must be put back.
+			name:  "failure part way",
+			patch: patch,
+			setup: func(t *testing.T) {
+				calls := 0
+				SetPermissionGate(func(action, path string) error {
+					if path == "b.txt" {
+						calls++
+						if calls > 1 {
+							return errors.New("b.txt became read-only")
+						}
+					}
+					return nil
+				})
+			},
+			want: "read-only",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			patchWorkspace(t, original)
+			if tt.setup != nil {
+				tt.setup(t)
+			}
+			_, err := applyPatch(tt.patch)
+			if err == nil || !strings.Contains(err.Error(), tt.want) || !strings.Contains(err.Error(), "no files were changed") {
+				t.Fatalf("err = %v, want one containing %q saying no files were changed", err, tt.want)
+			}
+			want := unchanged
+			if tt.files != nil {
+				want = tt.files
+			}
+			checkFiles(t, want)
+			if _, err := os.Stat("c"); err == nil {
+				t.Error("directory c was created")
+			}
+			if _, err := UndoLastChange(); err == nil {
+				t.Error("the failed patch left a change to undo")
+			}
+			// The files can be patched without being read again.
+			SetPermissionGate(nil)
+			if tt.files == nil {
+				if _, err := applyPatch(patch); err != nil {
+					t.Errorf("applying the patch afterwards: %v", err)
+				}
+			}
+		})
+	}
+}
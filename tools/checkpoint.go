@@ -0,0 +1,146 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// maxCheckpoints bounds how many changes can be undone.
+const maxCheckpoints = 100
+
+// checkpoint records how to revert a single filesystem change.
+type checkpoint struct {
+	description string
+	undo        func() error
+}
+
+var checkpoints struct {
+	mu    sync.Mutex
+	stack []checkpoint
+}
+
+// pushCheckpoint records a change that can later be reverted with UndoLastChange.
+func pushCheckpoint(description string, undo func() error) {
+	checkpoints.mu.Lock()
+	defer checkpoints.mu.Unlock()
+
+	checkpoints.stack = append(checkpoints.stack, checkpoint{description: description, undo: undo})
+	if len(checkpoints.stack) > maxCheckpoints {
+		checkpoints.stack = checkpoints.stack[len(checkpoints.stack)-maxCheckpoints:]
+	}
+}
+
+// UndoLastChange reverts the most recent change made by a tool and returns a
+// description of what was undone.
+func UndoLastChange() (string, error) {
+	checkpoints.mu.Lock()
+	defer checkpoints.mu.Unlock()
+
+	if len(checkpoints.stack) == 0 {
+		return "", fmt.Errorf("there are no changes to undo")
+	}
+	last := checkpoints.stack[len(checkpoints.stack)-1]
+	if err := last.undo(); err != nil {
+		return "", fmt.Errorf("failed to undo %s: %w", last.description, err)
+	}
+	checkpoints.stack = checkpoints.stack[:len(checkpoints.stack)-1]
+	return fmt.Sprintf("Undid: %s", last.description), nil
+}
+
+// ResetCheckpoints forgets every recorded change, so that none of them can
+// be undone any more.
+func ResetCheckpoints() {
+	checkpoints.mu.Lock()
+	defer checkpoints.mu.Unlock()
+	checkpoints.stack = nil
+}
+
+// backupFile captures the current state of path and returns a function that
+// restores it. If path does not exist, the restore function removes it.
+func backupFile(path string) (func() error, error) {
+	info, err := os.Lstat(path)
+	if os.IsNotExist(err) {
+		return func() error {
+			tracker.forget(path)
+			if err := os.Remove(path); err != nil && !os.IsNotExist(err) {
+				return err
+			}
+			return nil
+		}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if info.Mode()&os.ModeSymlink != 0 {
+		target, err := os.Readlink(path)
+		if err != nil {
+			return nil, err
+		}
+		return func() error {
+			_ = os.Remove(path)
+			return os.Symlink(target, path)
+		}, nil
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	mode := info.Mode().Perm()
+	return func() error {
+		tracker.forget(path)
+		if err := os.WriteFile(path, content, mode); err != nil {
+			return err
+		}
+		return os.Chmod(path, mode)
+	}, nil
+}
+
+// firstMissingDir returns the outermost ancestor of dir (or dir itself) that
+// does not exist yet, or "" if dir already exists.
+func firstMissingDir(dir string) string {
+	missing := ""
+	for d := dir; ; d = filepath.Dir(d) {
+		if _, err := os.Stat(d); err == nil {
+			return missing
+		}
+		missing = d
+		if filepath.Dir(d) == d {
+			return missing
+		}
+	}
+}
+
+// removeEmptyDirs removes dir and each of its parents up to and including
+// top, stopping at the first directory that is not empty.
+func removeEmptyDirs(dir, top string) error {
+	for d := dir; ; d = filepath.Dir(d) {
+		if err := os.Remove(d); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		if d == top || filepath.Dir(d) == d {
+			return nil
+		}
+	}
+}
+
+// undoChanges reverts the n most recent changes, most recent first, and
+// drops their checkpoints.
+func undoChanges(n int) error {
+	checkpoints.mu.Lock()
+	defer checkpoints.mu.Unlock()
+
+	var errs []error
+	for ; n > 0 && len(checkpoints.stack) > 0; n-- {
+		last := checkpoints.stack[len(checkpoints.stack)-1]
+		checkpoints.stack = checkpoints.stack[:len(checkpoints.stack)-1]
+		if err := last.undo(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to undo %s: %w", last.description, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -2,6 +2,7 @@ package tools
 
 import (
 	"encoding/json"
+	"fmt"
 
 	"github.com/anthropics/anthropic-sdk-go"
 	"github.com/invopop/jsonschema"
@@ -12,8 +13,17 @@ type ToolDefinition struct {
 	Description string                         `json:"description"`
 	InputSchema anthropic.ToolInputSchemaParam `json:"input_schema"`
 	Function    func(input json.RawMessage) (string, error)
+
+	// ReadOnly marks tools that never modify files or other state, which
+	// allows the agent to run several of them at once.
+	ReadOnly bool `json:"-"`
 }
 
+// GenerateSchema derives a tool input schema from the struct T. Fields
+// without omitempty in their json tag are required, nested structs and
+// slices are inlined, and unknown properties are disallowed. Enums, defaults
+// and examples can be set with jsonschema tags, e.g.
+// `jsonschema:"enum=read,enum=write,default=read"`.
 func GenerateSchema[T any]() anthropic.ToolInputSchemaParam {
 	reflector := jsonschema.Reflector{
 		AllowAdditionalProperties: false,
@@ -22,8 +32,17 @@ func GenerateSchema[T any]() anthropic.ToolInputSchemaParam {
 	var v T
 
 	schema := reflector.Reflect(v)
+	// The draft and type identifiers are noise to the model.
+	schema.Version = ""
+	schema.ID = ""
 
-	return anthropic.ToolInputSchemaParam{
-		Properties: schema.Properties,
+	data, err := json.Marshal(schema)
+	if err != nil {
+		panic(fmt.Sprintf("tools: cannot marshal schema for %T: %v", v, err))
+	}
+	result, err := SchemaFromJSON(data)
+	if err != nil {
+		panic(fmt.Sprintf("tools: invalid schema for %T: %v", v, err))
 	}
+	return result
 }
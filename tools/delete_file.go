@@ -0,0 +1,71 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+type DeleteFileInput struct {
+	Path string `json:"path" jsonschema_description:"The relative path of the file or empty directory to delete."`
+}
+
+func DeleteFile(input json.RawMessage) (string, error) {
+	deleteFileInput := DeleteFileInput{}
+	err := json.Unmarshal(input, &deleteFileInput)
+	if err != nil {
+		return "", err
+	}
+
+	if deleteFileInput.Path == "" {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+
+	path, rel, err := resolveInWorkspace(deleteFileInput.Path)
+	if err != nil {
+		return "", err
+	}
+	if rel == "." {
+		return "", fmt.Errorf("cannot delete the workspace root")
+	}
+	info, err := os.Lstat(path)
+	if err != nil {
+		return "", err
+	}
+	if err := checkPermission("delete", rel); err != nil {
+		return "", err
+	}
+
+	if info.IsDir() {
+		if err := os.Remove(path); err != nil {
+			return "", fmt.Errorf("failed to delete directory %s (only empty directories can be deleted): %w", rel, err)
+		}
+		pushCheckpoint("delete directory "+rel, func() error {
+			return os.Mkdir(path, info.Mode().Perm())
+		})
+		return fmt.Sprintf("Deleted directory %s", rel), nil
+	}
+
+	restore, err := backupFile(path)
+	if err != nil {
+		return "", err
+	}
+	if err := os.Remove(path); err != nil {
+		return "", err
+	}
+	tracker.forget(path)
+	noteChanged(path)
+	pushCheckpoint("delete "+rel, restore)
+
+	return fmt.Sprintf("Deleted %s", rel), nil
+}
+
+var DeleteFileDefinition = ToolDefinition{
+	Name: "delete_file",
+	Description: `Delete a file or an empty directory within the workspace.
+Non-empty directories must be emptied first. Paths outside the workspace and inside .git are refused.
+The deletion can be reverted with undo_last_change.
+`,
+	InputSchema: GenerateSchema[DeleteFileInput](),
+	Function:    DeleteFile,
+}
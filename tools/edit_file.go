@@ -25,6 +25,10 @@ func EditFile(input json.RawMessage) (string, error) {
 		return "", fmt.Errorf("invalid input parameters")
 	}
 
+	if _, _, err := resolveInWorkspace(editFileInput.Path); err != nil {
+		return "", err
+	}
+
 	content, err := os.ReadFile(editFileInput.Path)
 	if err != nil {
 		if os.IsNotExist(err) && editFileInput.OldStr == "" {
@@ -32,20 +36,23 @@ func EditFile(input json.RawMessage) (string, error) {
 		}
 		return "", err
 	}
+	if err := tracker.checkFresh(editFileInput.Path, content); err != nil {
+		return "", err
+	}
 
-	oldContent := string(content)
-	newContent := strings.Replace(oldContent, editFileInput.OldStr, editFileInput.NewStr, -1)
+	oldContent := normalizeNewlines(string(content))
+	newContent := strings.Replace(oldContent, normalizeNewlines(editFileInput.OldStr), normalizeNewlines(editFileInput.NewStr), -1)
 
 	if oldContent == newContent && editFileInput.OldStr != "" {
 		return "", fmt.Errorf("old_str not found in file")
 	}
 
-	err = os.WriteFile(editFileInput.Path, []byte(newContent), 0644)
+	written, note, err := writeFile(editFileInput.Path, newContent)
 	if err != nil {
 		return "", err
 	}
 
-	return "OK", nil
+	return diffResult(editFileInput.Path, oldContent, written) + note, nil
 }
 
 func createNewFile(filePath, content string) (string, error) {
@@ -57,19 +64,20 @@ func createNewFile(filePath, content string) (string, error) {
 		}
 	}
 
-	err := os.WriteFile(filePath, []byte(content), 0644)
+	_, note, err := writeFile(filePath, content)
 	if err != nil {
 		return "", fmt.Errorf("failed to create file: %w", err)
 	}
 
-	return fmt.Sprintf("Successfully created file %s", filePath), nil
+	return fmt.Sprintf("Successfully created file %s", filePath) + note, nil
 }
 
 var EditFileDefinition = ToolDefinition{
 	Name: "edit_file",
 	Description: `Make edits to a text file.
 Replaces 'old_str' with 'new_str' in the given file. 'old_str' and 'new_str' MUST be different from each other.
-If the file specified with path doesn't exist, it will be created.
+If the file specified with path doesn't exist, it will be created. An existing file must have been read with read_file first, and is refused if it changed on disk since.
+Returns a unified diff of the change.
 `,
 	InputSchema: GenerateSchema[EditFileInput](),
 	Function:    EditFile,
@@ -0,0 +1,148 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// defaultExternalTimeout bounds an external tool run when its manifest does
+// not set a timeout.
+const defaultExternalTimeout = 2 * time.Minute
+
+// ExternalManifest describes a tool implemented by an external executable.
+// The executable receives the tool input as JSON on stdin and writes its
+// result to stdout; a non-zero exit status marks the result as an error,
+// with stderr as the message.
+type ExternalManifest struct {
+	Name        string          `json:"name"`
+	Description string          `json:"description"`
+	InputSchema json.RawMessage `json:"input_schema"`
+	// Command is the executable and its arguments. A relative executable path
+	// is resolved against the manifest's directory.
+	Command        []string `json:"command"`
+	TimeoutSeconds int      `json:"timeout_seconds,omitempty"`
+	// ReadOnly declares that the tool does not modify anything, so it may
+	// run concurrently with other read-only tools.
+	ReadOnly bool `json:"read_only,omitempty"`
+}
+
+// LoadExternalTool reads a manifest and returns the tool it describes.
+func LoadExternalTool(manifestPath string) (ToolDefinition, error) {
+	data, err := os.ReadFile(manifestPath)
+	if err != nil {
+		return ToolDefinition{}, err
+	}
+	var manifest ExternalManifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return ToolDefinition{}, fmt.Errorf("invalid manifest: %w", err)
+	}
+	if manifest.Name == "" || manifest.Description == "" || len(manifest.Command) == 0 {
+		return ToolDefinition{}, fmt.Errorf("manifest must set name, description and command")
+	}
+
+	schema, err := SchemaFromJSON(manifest.InputSchema)
+	if err != nil {
+		return ToolDefinition{}, err
+	}
+
+	command := append([]string(nil), manifest.Command...)
+	if strings.ContainsRune(command[0], filepath.Separator) && !filepath.IsAbs(command[0]) {
+		command[0] = filepath.Join(filepath.Dir(manifestPath), command[0])
+	}
+	timeout := defaultExternalTimeout
+	if manifest.TimeoutSeconds > 0 {
+		timeout = time.Duration(manifest.TimeoutSeconds) * time.Second
+	}
+
+	return ToolDefinition{
+		Name:        manifest.Name,
+		Description: manifest.Description,
+		InputSchema: schema,
+		Function: func(input json.RawMessage) (string, error) {
+			return runExternalTool(command, timeout, input)
+		},
+		ReadOnly: manifest.ReadOnly,
+	}, nil
+}
+
+// SchemaFromJSON converts a JSON schema object into a tool input schema.
+func SchemaFromJSON(data json.RawMessage) (anthropic.ToolInputSchemaParam, error) {
+	if len(data) == 0 {
+		return anthropic.ToolInputSchemaParam{Properties: map[string]any{}}, nil
+	}
+	var schema map[string]any
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return anthropic.ToolInputSchemaParam{}, fmt.Errorf("invalid input_schema: %w", err)
+	}
+	if t, ok := schema["type"]; ok && t != "object" {
+		return anthropic.ToolInputSchemaParam{}, fmt.Errorf("input_schema must have type \"object\", not %v", t)
+	}
+
+	result := anthropic.ToolInputSchemaParam{Properties: schema["properties"]}
+	if result.Properties == nil {
+		result.Properties = map[string]any{}
+	}
+	extras := make(map[string]any)
+	for key, value := range schema {
+		if key != "type" && key != "properties" {
+			extras[key] = value
+		}
+	}
+	if len(extras) > 0 {
+		result.WithExtraFields(extras)
+	}
+	return result, nil
+}
+
+// runExternalTool runs command with input on stdin and returns its stdout.
+func runExternalTool(command []string, timeout time.Duration, input json.RawMessage) (string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	cmd := exec.CommandContext(ctx, command[0], command[1:]...)
+	cmd.Dir = WorkspaceRoot()
+	cmd.Stdin = bytes.NewReader(input)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return "", fmt.Errorf("%s timed out after %s", filepath.Base(command[0]), timeout)
+	}
+	if err != nil {
+		message := strings.TrimSpace(stderr.String())
+		if message == "" {
+			message = strings.TrimSpace(stdout.String())
+		}
+		if message == "" {
+			message = err.Error()
+		}
+		return "", fmt.Errorf("%s", message)
+	}
+	return stdout.String(), nil
+}
+
+// SchemaJSON renders a tool input schema as a JSON schema object; it is the
+// inverse of SchemaFromJSON. Use it rather than marshalling the schema: the
+// SDK also writes its ExtraFields field, as "-".
+func SchemaJSON(schema anthropic.ToolInputSchemaParam) (json.RawMessage, error) {
+	object := make(map[string]any)
+	for key, value := range schema.GetExtraFields() {
+		object[key] = value
+	}
+	object["type"] = "object"
+	if schema.Properties != nil {
+		object["properties"] = schema.Properties
+	}
+	return json.Marshal(object)
+}
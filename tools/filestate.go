@@ -0,0 +1,108 @@
+package tools
+
+import (
+	"crypto/sha256"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+// fileSnapshot is what the agent last saw of a file on disk.
+type fileSnapshot struct {
+	modTime time.Time
+	hash    [sha256.Size]byte
+}
+
+// fileTracker remembers the state of every file returned by read_file or
+// written by a tool during the current session, so that edits are only made
+// against content the agent has actually seen.
+type fileTracker struct {
+	mu    sync.Mutex
+	files map[string]fileSnapshot
+}
+
+var tracker = &fileTracker{files: make(map[string]fileSnapshot)}
+
+var changedFiles struct {
+	mu    sync.Mutex
+	paths []string
+}
+
+// noteChanged records that a tool created, modified, moved or deleted path.
+func noteChanged(path string) {
+	changedFiles.mu.Lock()
+	defer changedFiles.mu.Unlock()
+	changedFiles.paths = append(changedFiles.paths, path)
+}
+
+// TakeChangedFiles returns the paths changed by tools since the last call.
+func TakeChangedFiles() []string {
+	changedFiles.mu.Lock()
+	defer changedFiles.mu.Unlock()
+	paths := changedFiles.paths
+	changedFiles.paths = nil
+	return paths
+}
+
+// ResetFileTracker forgets every file read so far. The tracker starts empty,
+// so only programs running several sessions in one process, such as evals
+// moving from one task's workspace to the next, need to call it.
+func ResetFileTracker() {
+	tracker.mu.Lock()
+	defer tracker.mu.Unlock()
+	tracker.files = make(map[string]fileSnapshot)
+}
+
+// trackerKey normalizes a path so different spellings of the same file share an entry.
+func trackerKey(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return filepath.Clean(path)
+}
+
+// record stores the current state of path with the given content.
+func (t *fileTracker) record(path string, content []byte) {
+	snapshot := fileSnapshot{hash: sha256.Sum256(content)}
+	if info, err := os.Stat(path); err == nil {
+		snapshot.modTime = info.ModTime()
+	}
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.files[trackerKey(path)] = snapshot
+}
+
+// forget drops path from the tracker, e.g. after it has been deleted.
+func (t *fileTracker) forget(path string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.files, trackerKey(path))
+}
+
+// checkFresh returns an error unless path was read during this session and
+// its content on disk still matches what the agent last saw.
+func (t *fileTracker) checkFresh(path string, content []byte) error {
+	t.mu.Lock()
+	snapshot, ok := t.files[trackerKey(path)]
+	t.mu.Unlock()
+
+	if !ok {
+		return fmt.Errorf("%s has not been read in this session; use read_file to view its current content before editing it", path)
+	}
+	if snapshot.hash == sha256.Sum256(content) {
+		return nil
+	}
+
+	changed := ""
+	if info, err := os.Stat(path); err == nil && !info.ModTime().Equal(snapshot.modTime) {
+		changed = fmt.Sprintf(" (last read version from %s, now modified at %s)",
+			snapshot.modTime.Format(time.TimeOnly), info.ModTime().Format(time.TimeOnly))
+	}
+	return fmt.Errorf("%s was modified on disk since it was last read%s; use read_file to get the current content before editing it", path, changed)
+}
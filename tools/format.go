@@ -0,0 +1,123 @@
+package tools
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/tools/imports"
+)
+
+// PostWriteHook rewrites the content a tool is about to write to path, for
+// example by running a formatter. Hooks run in registration order after the
+// tool has produced its new content and before the file is committed to
+// disk, so each change results in a single write. A hook that does not apply
+// to path returns content unchanged.
+type PostWriteHook func(path, content string) (string, error)
+
+var postWriteHooks struct {
+	mu    sync.Mutex
+	hooks []PostWriteHook
+}
+
+// RegisterPostWriteHook adds a hook to run on every file written by a tool.
+func RegisterPostWriteHook(hook PostWriteHook) {
+	postWriteHooks.mu.Lock()
+	defer postWriteHooks.mu.Unlock()
+	postWriteHooks.hooks = append(postWriteHooks.hooks, hook)
+}
+
+// runPostWriteHooks passes content through every registered hook. A failing
+// hook is skipped and its error reported alongside the content produced so far.
+func runPostWriteHooks(path, content string) (string, []error) {
+	postWriteHooks.mu.Lock()
+	hooks := append([]PostWriteHook(nil), postWriteHooks.hooks...)
+	postWriteHooks.mu.Unlock()
+
+	var errs []error
+	for _, hook := range hooks {
+		formatted, err := hook(path, content)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		content = formatted
+	}
+	return content, errs
+}
+
+// GoImportsHook formats Go files like goimports: it runs gofmt and adds
+// missing or removes unused imports. Files that do not parse are left alone
+// so that the build check can report the syntax error.
+func GoImportsHook(path, content string) (string, error) {
+	if filepath.Ext(path) != ".go" {
+		return content, nil
+	}
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		abs = path
+	}
+	formatted, err := imports.Process(abs, []byte(content), &imports.Options{
+		Comments:  true,
+		TabIndent: true,
+		TabWidth:  8,
+	})
+	if err != nil {
+		return content, nil
+	}
+	return string(formatted), nil
+}
+
+// formatterTimeout bounds how long an external formatter may run on a file.
+const formatterTimeout = 30 * time.Second
+
+// ExternalFormatterHook returns a hook that pipes files with one of the given
+// extensions through command, which reads the content on stdin and writes the
+// formatted content to stdout. The argument "{path}" is replaced by the
+// file's path. A formatter that times out or prints nothing for a non-empty
+// file leaves the content unchanged.
+func ExternalFormatterHook(extensions, command []string) PostWriteHook {
+	return func(path, content string) (string, error) {
+		matched := false
+		for _, ext := range extensions {
+			if strings.EqualFold(filepath.Ext(path), ext) {
+				matched = true
+				break
+			}
+		}
+		if !matched || len(command) == 0 {
+			return content, nil
+		}
+
+		args := make([]string, len(command)-1)
+		for i, arg := range command[1:] {
+			args[i] = strings.ReplaceAll(arg, "{path}", path)
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), formatterTimeout)
+		defer cancel()
+		cmd := exec.CommandContext(ctx, command[0], args...)
+		cmd.Dir = WorkspaceRoot()
+		cmd.Stdin = strings.NewReader(content)
+		var stdout, stderr bytes.Buffer
+		cmd.Stdout = &stdout
+		cmd.Stderr = &stderr
+		err := cmd.Run()
+		if ctx.Err() == context.DeadlineExceeded {
+			return content, fmt.Errorf("formatter %s timed out after %s on %s", command[0], formatterTimeout, path)
+		}
+		if err != nil {
+			return content, fmt.Errorf("formatter %s failed on %s: %v: %s", command[0], path, err, strings.TrimSpace(stderr.String()))
+		}
+		// A formatter that writes in place, or fails without saying so,
+		// would otherwise empty the file.
+		if stdout.Len() == 0 && content != "" {
+			return content, fmt.Errorf("formatter %s printed nothing for %s; it must write the formatted content to stdout", command[0], path)
+		}
+		return stdout.String(), nil
+	}
+}
@@ -0,0 +1,31 @@
+package tools
+
+import (
+	"os/exec"
+	"strings"
+	"testing"
+)
+
+func TestExternalFormatterHook(t *testing.T) {
+	for _, name := range []string{"tr", "true"} {
+		if _, err := exec.LookPath(name); err != nil {
+			t.Skipf("%s not found", name)
+		}
+	}
+	upper := ExternalFormatterHook([]string{".txt"}, []string{"tr", "a-z", "A-Z"})
+	if got, err := upper("notes.txt", "hello\n"); err != nil || got != "HELLO\n" {
+		t.Errorf("hook = %q, %v; want HELLO", got, err)
+	}
+	if got, err := upper("main.go", "hello\n"); err != nil || got != "hello\n" {
+		t.Errorf("hook on another extension = %q, %v; want the content unchanged", got, err)
+	}
+
+	silent := ExternalFormatterHook([]string{".txt"}, []string{"true"})
+	got, err := silent("notes.txt", "hello\n")
+	if err == nil || !strings.Contains(err.Error(), "printed nothing") || got != "hello\n" {
+		t.Errorf("silent formatter = %q, %v; want the content unchanged and an error", got, err)
+	}
+	if got, err := silent("empty.txt", ""); err != nil || got != "" {
+		t.Errorf("silent formatter on an empty file = %q, %v", got, err)
+	}
+}
@@ -0,0 +1,291 @@
+package tools
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// goCheckTimeout bounds a full build, vet and test run.
+const goCheckTimeout = 5 * time.Minute
+
+type GoCheckInput struct {
+	Packages  []string `json:"packages,omitempty" jsonschema_description:"Optional package patterns to check, e.g. ['./tools'] or ['./...']. Defaults to ['./...']."`
+	Run       string   `json:"run,omitempty" jsonschema_description:"Optional regular expression passed to 'go test -run' to select tests."`
+	SkipTests bool     `json:"skip_tests,omitempty" jsonschema_description:"Only run 'go build' and 'go vet', skipping 'go test'."`
+}
+
+// GoDiagnostic is a single problem reported by go build, go vet or go test.
+type GoDiagnostic struct {
+	Step    string `json:"step"`
+	File    string `json:"file,omitempty"`
+	Line    int    `json:"line,omitempty"`
+	Column  int    `json:"column,omitempty"`
+	Message string `json:"message"`
+	Package string `json:"package,omitempty"`
+	Test    string `json:"test,omitempty"`
+	Output  string `json:"output,omitempty"`
+}
+
+// GoCheckResult is the outcome of a go_check run.
+type GoCheckResult struct {
+	Passed      bool           `json:"passed"`
+	Steps       []string       `json:"steps"`
+	Diagnostics []GoDiagnostic `json:"diagnostics"`
+}
+
+// String renders the result as one line per diagnostic, followed by any test output.
+func (r GoCheckResult) String() string {
+	if r.Passed {
+		return fmt.Sprintf("go %s passed", strings.Join(r.Steps, ", "))
+	}
+	var out strings.Builder
+	for _, d := range r.Diagnostics {
+		location := d.Package
+		if d.File != "" {
+			location = fmt.Sprintf("%s:%d", d.File, d.Line)
+			if d.Column > 0 {
+				location += fmt.Sprintf(":%d", d.Column)
+			}
+		}
+		fmt.Fprintf(&out, "%s: %s: %s\n", d.Step, location, d.Message)
+		if d.Output != "" {
+			out.WriteString(indent(d.Output, "    "))
+		}
+	}
+	return out.String()
+}
+
+// indent prefixes every line of text.
+func indent(text, prefix string) string {
+	var out strings.Builder
+	for _, line := range strings.SplitAfter(strings.TrimRight(text, "\n")+"\n", "\n") {
+		if line != "" {
+			out.WriteString(prefix + line)
+		}
+	}
+	return out.String()
+}
+
+var goPositionRe = regexp.MustCompile(`^(?:vet: )?(\S+\.go):(\d+)(?::(\d+))?: (.*)$`)
+
+// parseGoPosition extracts a diagnostic from a "file.go:line:col: message" line.
+func parseGoPosition(step, line string) (GoDiagnostic, bool) {
+	m := goPositionRe.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return GoDiagnostic{}, false
+	}
+	d := GoDiagnostic{Step: step, File: m[1], Message: m[4]}
+	d.Line, _ = strconv.Atoi(m[2])
+	d.Column, _ = strconv.Atoi(m[3])
+	if filepath.IsAbs(d.File) {
+		d.File = workspaceRelative(d.File)
+	}
+	return d, true
+}
+
+// parseCompilerOutput turns go build or go vet output into diagnostics.
+// Indented lines continue the previous diagnostic's message.
+func parseCompilerOutput(step, output string) []GoDiagnostic {
+	var diagnostics []GoDiagnostic
+	pkg := ""
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasPrefix(line, "# "):
+			pkg = strings.TrimPrefix(line, "# ")
+		case strings.HasPrefix(line, "\t") && len(diagnostics) > 0:
+			diagnostics[len(diagnostics)-1].Message += "\n" + strings.TrimSpace(line)
+		default:
+			if d, ok := parseGoPosition(step, line); ok {
+				d.Package = pkg
+				diagnostics = append(diagnostics, d)
+			} else if strings.TrimSpace(line) != "" && !strings.HasPrefix(line, "ok ") {
+				diagnostics = append(diagnostics, GoDiagnostic{Step: step, Package: pkg, Message: strings.TrimSpace(line)})
+			}
+		}
+	}
+	return diagnostics
+}
+
+// goTestEvent is the subset of the 'go test -json' event stream used here.
+type goTestEvent struct {
+	Action      string
+	Package     string
+	Test        string
+	Output      string
+	FailedBuild string
+}
+
+// parseTestOutput turns a 'go test -json' stream into diagnostics for build
+// failures and failing tests.
+func parseTestOutput(output []byte) []GoDiagnostic {
+	var diagnostics []GoDiagnostic
+	testOutput := make(map[string]*strings.Builder)
+	failedTests := make(map[string]bool)
+	var buildOutput strings.Builder
+
+	scanner := bufio.NewScanner(bytes.NewReader(output))
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for scanner.Scan() {
+		var event goTestEvent
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			// Early errors are printed as plain text.
+			buildOutput.WriteString(scanner.Text() + "\n")
+			continue
+		}
+
+		key := event.Package + " " + event.Test
+		switch event.Action {
+		case "build-output":
+			buildOutput.WriteString(event.Output)
+		case "output":
+			if testOutput[key] == nil {
+				testOutput[key] = &strings.Builder{}
+			}
+			testOutput[key].WriteString(event.Output)
+		case "fail":
+			if event.Test == "" {
+				// A package can fail outside any test, e.g. by panicking in init.
+				if event.FailedBuild == "" && !failedTests[event.Package] {
+					d := GoDiagnostic{Step: "test", Package: event.Package, Message: "package failed"}
+					if out := testOutput[key]; out != nil {
+						d.Output = out.String()
+					}
+					diagnostics = append(diagnostics, d)
+				}
+				continue
+			}
+			failedTests[event.Package] = true
+			d := GoDiagnostic{Step: "test", Package: event.Package, Test: event.Test, Message: "test failed"}
+			if out := testOutput[key]; out != nil {
+				d.Output = out.String()
+				// Point at the first file:line reported by t.Error and friends.
+				for _, line := range strings.Split(d.Output, "\n") {
+					if pos, ok := parseGoPosition("test", line); ok {
+						d.File, d.Line, d.Message = pos.File, pos.Line, pos.Message
+						break
+					}
+				}
+			}
+			diagnostics = append(diagnostics, d)
+		}
+	}
+
+	for _, d := range parseCompilerOutput("test", buildOutput.String()) {
+		if strings.HasPrefix(d.Message, "FAIL") {
+			continue
+		}
+		diagnostics = append(diagnostics, d)
+	}
+	return diagnostics
+}
+
+// runGo runs a go subcommand in the workspace and returns its combined output.
+// A non-zero exit status is reported through the output, not the error.
+func runGo(ctx context.Context, args ...string) ([]byte, error) {
+	cmd := exec.CommandContext(ctx, "go", args...)
+	cmd.Dir = WorkspaceRoot()
+	output, err := cmd.CombinedOutput()
+	if _, ok := err.(*exec.ExitError); ok {
+		return output, nil
+	}
+	return output, err
+}
+
+// RunGoCheck runs go build and go vet, and go test unless skipped, stopping
+// at the first step that reports problems.
+func RunGoCheck(ctx context.Context, input GoCheckInput) (GoCheckResult, error) {
+	ctx, cancel := context.WithTimeout(ctx, goCheckTimeout)
+	defer cancel()
+
+	packages := input.Packages
+	if len(packages) == 0 {
+		packages = []string{"./..."}
+	}
+	// A pattern starting with "-" would be parsed as a flag to go, e.g.
+	// -exec or -toolexec, which runs an arbitrary command.
+	for _, pkg := range packages {
+		if strings.HasPrefix(pkg, "-") {
+			return GoCheckResult{}, fmt.Errorf("invalid package pattern %q: patterns may not start with '-'", pkg)
+		}
+	}
+
+	result := GoCheckResult{Diagnostics: []GoDiagnostic{}}
+	for _, step := range []string{"build", "vet", "test"} {
+		if step == "test" && input.SkipTests {
+			break
+		}
+		result.Steps = append(result.Steps, step)
+
+		var diagnostics []GoDiagnostic
+		switch step {
+		case "build":
+			output, err := runGo(ctx, append([]string{"build", "-o", os.DevNull}, packages...)...)
+			if err != nil {
+				return result, err
+			}
+			diagnostics = parseCompilerOutput(step, string(output))
+		case "vet":
+			output, err := runGo(ctx, append([]string{"vet"}, packages...)...)
+			if err != nil {
+				return result, err
+			}
+			diagnostics = parseCompilerOutput(step, string(output))
+		case "test":
+			args := []string{"test", "-json"}
+			if input.Run != "" {
+				args = append(args, "-run="+input.Run)
+			}
+			output, err := runGo(ctx, append(args, packages...)...)
+			if err != nil {
+				return result, err
+			}
+			diagnostics = parseTestOutput(output)
+		}
+
+		if len(diagnostics) > 0 {
+			result.Diagnostics = diagnostics
+			return result, nil
+		}
+	}
+	result.Passed = true
+	return result, nil
+}
+
+func GoCheck(input json.RawMessage) (string, error) {
+	goCheckInput := GoCheckInput{}
+	err := json.Unmarshal(input, &goCheckInput)
+	if err != nil {
+		return "", err
+	}
+
+	result, err := RunGoCheck(context.Background(), goCheckInput)
+	if err != nil {
+		return "", err
+	}
+
+	output, err := json.Marshal(result)
+	if err != nil {
+		return "", err
+	}
+	return string(output), nil
+}
+
+var GoCheckDefinition = ToolDefinition{
+	Name: "go_check",
+	Description: `Run 'go build', 'go vet' and 'go test' on the workspace module and return structured diagnostics as JSON.
+Each diagnostic has the step (build, vet or test), file, line, column and message; failing tests also include the test name and its output.
+Steps run in order and stop at the first one that reports problems. Use 'run' to select tests and 'skip_tests' for a quick compile check.
+`,
+	InputSchema: GenerateSchema[GoCheckInput](),
+	Function:    GoCheck,
+}
@@ -0,0 +1,16 @@
+package tools
+
+import (
+	"context"
+	"strings"
+	"testing"
+)
+
+func TestRunGoCheckRejectsFlags(t *testing.T) {
+	for _, pkg := range []string{"-toolexec=touch pwned", "-exec=sh"} {
+		_, err := RunGoCheck(context.Background(), GoCheckInput{Packages: []string{"./...", pkg}})
+		if err == nil || !strings.Contains(err.Error(), "may not start with '-'") {
+			t.Errorf("RunGoCheck(%q): err = %v, want the pattern refused", pkg, err)
+		}
+	}
+}
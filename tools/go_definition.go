@@ -0,0 +1,38 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+func GoDefinition(input json.RawMessage) (string, error) {
+	goPositionInput := GoPositionInput{}
+	err := json.Unmarshal(input, &goPositionInput)
+	if err != nil {
+		return "", err
+	}
+
+	pkg, id, err := findIdentifier(goPositionInput)
+	if err != nil {
+		return "", err
+	}
+	obj := objectOf(pkg, id)
+	if obj == nil {
+		return "", fmt.Errorf("no type information for %q; the package may not compile", id.Name)
+	}
+	if !obj.Pos().IsValid() {
+		return fmt.Sprintf("%s is predeclared by the language\n%s", obj.Name(), describeObject(obj)), nil
+	}
+
+	return fmt.Sprintf("%s\n%s", formatPosition(pkg.Fset.Position(obj.Pos())), describeObject(obj)), nil
+}
+
+var GoDefinitionDefinition = ToolDefinition{
+	Name: "go_definition",
+	Description: `Find where a Go identifier is declared, using the type checker rather than text search.
+Give the file, the line and the identifier as written on that line. Returns the declaration's file:line:column and its signature, including for identifiers from the standard library or dependencies.
+`,
+	InputSchema: GenerateSchema[GoPositionInput](),
+	Function:    GoDefinition,
+	ReadOnly:    true,
+}
@@ -0,0 +1,148 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go/ast"
+	"go/doc"
+	"go/printer"
+	"go/token"
+	"strings"
+)
+
+type GoDocInput struct {
+	Package string `json:"package,omitempty" jsonschema_description:"Package import path (e.g. 'net/http') or relative directory (e.g. './agent'). Defaults to the package in the current directory."`
+	Symbol  string `json:"symbol,omitempty" jsonschema_description:"Optional symbol to document, e.g. 'NewAgent', 'Agent' or 'Agent.Run'. If omitted, the package overview is returned."`
+}
+
+// formatDecl prints a declaration node without function bodies.
+func formatDecl(fset *token.FileSet, decl ast.Node) string {
+	var buf bytes.Buffer
+	if err := printer.Fprint(&buf, fset, decl); err != nil {
+		return ""
+	}
+	return buf.String()
+}
+
+// docEntry renders a declaration followed by its doc comment.
+func docEntry(fset *token.FileSet, decl ast.Node, comment string) string {
+	entry := formatDecl(fset, decl)
+	if comment = strings.TrimSpace(comment); comment != "" {
+		entry += "\n\n" + comment
+	}
+	return entry
+}
+
+// findDoc looks up symbol ("Name" or "Type.Method") in a documented package.
+func findDoc(fset *token.FileSet, pkg *doc.Package, symbol string) (string, bool) {
+	typeName, member, isMember := strings.Cut(symbol, ".")
+
+	for _, values := range [][]*doc.Value{pkg.Consts, pkg.Vars} {
+		for _, v := range values {
+			for _, name := range v.Names {
+				if !isMember && name == symbol {
+					return docEntry(fset, v.Decl, v.Doc), true
+				}
+			}
+		}
+	}
+	for _, f := range pkg.Funcs {
+		if !isMember && f.Name == symbol {
+			return docEntry(fset, f.Decl, f.Doc), true
+		}
+	}
+	for _, t := range pkg.Types {
+		if t.Name != typeName {
+			continue
+		}
+		if !isMember {
+			entry := docEntry(fset, t.Decl, t.Doc)
+			for _, f := range t.Funcs {
+				entry += "\n\n" + formatDecl(fset, f.Decl)
+			}
+			for _, m := range t.Methods {
+				entry += "\n" + formatDecl(fset, m.Decl)
+			}
+			return entry, true
+		}
+		for _, m := range t.Methods {
+			if m.Name == member {
+				return docEntry(fset, m.Decl, m.Doc), true
+			}
+		}
+	}
+	return "", false
+}
+
+func GoDoc(input json.RawMessage) (string, error) {
+	goDocInput := GoDocInput{}
+	err := json.Unmarshal(input, &goDocInput)
+	if err != nil {
+		return "", err
+	}
+	pattern := goDocInput.Package
+	if pattern == "" {
+		pattern = "."
+	}
+
+	pkgs, err := loadGoPackages(goSyntaxLoadMode, false, pattern)
+	if err != nil {
+		return "", err
+	}
+	pkg := pkgs[0]
+	if len(pkg.Syntax) == 0 {
+		return "", fmt.Errorf("package %s has no Go source files", pattern)
+	}
+
+	mode := doc.Mode(0)
+	if goDocInput.Symbol != "" {
+		mode = doc.AllDecls
+	}
+	docPkg, err := doc.NewFromFiles(pkg.Fset, pkg.Syntax, pkg.PkgPath, mode)
+	if err != nil {
+		return "", err
+	}
+
+	if goDocInput.Symbol != "" {
+		entry, ok := findDoc(pkg.Fset, docPkg, goDocInput.Symbol)
+		if !ok {
+			return "", fmt.Errorf("no symbol %s in package %s", goDocInput.Symbol, pkg.PkgPath)
+		}
+		return entry, nil
+	}
+
+	var result strings.Builder
+	fmt.Fprintf(&result, "package %s // import %q\n\n", docPkg.Name, pkg.PkgPath)
+	if docPkg.Doc != "" {
+		result.WriteString(strings.TrimSpace(docPkg.Doc) + "\n\n")
+	}
+	for _, values := range [][]*doc.Value{docPkg.Consts, docPkg.Vars} {
+		for _, v := range values {
+			result.WriteString(formatDecl(pkg.Fset, v.Decl) + "\n")
+		}
+	}
+	for _, f := range docPkg.Funcs {
+		result.WriteString(formatDecl(pkg.Fset, f.Decl) + "\n")
+	}
+	for _, t := range docPkg.Types {
+		result.WriteString(formatDecl(pkg.Fset, t.Decl) + "\n")
+		for _, f := range t.Funcs {
+			result.WriteString("    " + formatDecl(pkg.Fset, f.Decl) + "\n")
+		}
+		for _, m := range t.Methods {
+			result.WriteString("    " + formatDecl(pkg.Fset, m.Decl) + "\n")
+		}
+	}
+	return result.String(), nil
+}
+
+var GoDocDefinition = ToolDefinition{
+	Name: "go_doc",
+	Description: `Show Go documentation for a package or one of its symbols, like 'go doc'.
+Works offline for workspace packages, the standard library and downloaded dependencies. Without a symbol, returns the package overview with all exported declarations; with a symbol (e.g. 'Agent' or 'Agent.Run'), returns its declaration and doc comment.
+`,
+	InputSchema: GenerateSchema[GoDocInput](),
+	Function:    GoDoc,
+	ReadOnly:    true,
+}
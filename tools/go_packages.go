@@ -0,0 +1,177 @@
+package tools
+
+import (
+	"fmt"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// goTypesLoadMode type-checks packages and their dependencies from source, so
+// that loading does not depend on the toolchain's export data format.
+const goTypesLoadMode = packages.NeedName | packages.NeedFiles | packages.NeedCompiledGoFiles |
+	packages.NeedImports | packages.NeedDeps | packages.NeedSyntax | packages.NeedTypes | packages.NeedTypesInfo
+
+// goSyntaxLoadMode only parses packages, which is enough for documentation.
+const goSyntaxLoadMode = packages.NeedName | packages.NeedFiles | packages.NeedSyntax
+
+// loadGoPackages loads the packages matching patterns in the workspace,
+// including their test variants if tests is set.
+func loadGoPackages(mode packages.LoadMode, tests bool, patterns ...string) ([]*packages.Package, error) {
+	return runGoPackagesLoad(&packages.Config{Mode: mode, Tests: tests}, patterns)
+}
+
+// loadWorkspaceGoPackages type-checks the workspace packages matching
+// patterns and their test variants. Dependencies outside the workspace are
+// checked without their function bodies: their declarations are all the
+// workspace can refer to, and checking the bodies of the standard library
+// and every module took most of the time.
+func loadWorkspaceGoPackages(patterns ...string) ([]*packages.Package, error) {
+	return runGoPackagesLoad(&packages.Config{
+		Mode:      goTypesLoadMode,
+		Tests:     true,
+		ParseFile: parseDeclarationsOutsideWorkspace,
+	}, patterns)
+}
+
+// parseDeclarationsOutsideWorkspace parses a Go file for packages.Load,
+// dropping comments and function bodies from files outside the workspace.
+// Type errors this causes in those packages, such as unused imports, are
+// expected.
+func parseDeclarationsOutsideWorkspace(fset *token.FileSet, filename string, src []byte) (*ast.File, error) {
+	if rel, err := filepath.Rel(WorkspaceRoot(), filename); err == nil && !strings.HasPrefix(rel, "..") {
+		return parser.ParseFile(fset, filename, src, parser.AllErrors|parser.ParseComments)
+	}
+	file, err := parser.ParseFile(fset, filename, src, parser.SkipObjectResolution)
+	if file != nil {
+		for _, decl := range file.Decls {
+			if fn, ok := decl.(*ast.FuncDecl); ok {
+				fn.Body = nil
+			}
+		}
+	}
+	return file, err
+}
+
+// runGoPackagesLoad runs packages.Load with cfg in the workspace. The module
+// proxy is disabled so that loading never touches the network.
+func runGoPackagesLoad(cfg *packages.Config, patterns []string) ([]*packages.Package, error) {
+	cfg.Dir = WorkspaceRoot()
+	cfg.Env = append(os.Environ(), "GOPROXY=off", "GOFLAGS=-mod=readonly")
+	pkgs, err := packages.Load(cfg, patterns...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to load Go packages: %w", err)
+	}
+	if len(pkgs) == 0 {
+		return nil, fmt.Errorf("no Go packages match %s", strings.Join(patterns, " "))
+	}
+	return pkgs, nil
+}
+
+// workspaceRelative shortens an absolute file name to a workspace-relative path when possible.
+func workspaceRelative(filename string) string {
+	if rel, err := filepath.Rel(WorkspaceRoot(), filename); err == nil && !strings.HasPrefix(rel, "..") {
+		return rel
+	}
+	return filename
+}
+
+// formatPosition renders a position as path:line:column.
+func formatPosition(pos token.Position) string {
+	return fmt.Sprintf("%s:%d:%d", workspaceRelative(pos.Filename), pos.Line, pos.Column)
+}
+
+// objectKey identifies a declaration independently of which package load produced the object.
+func objectKey(fset *token.FileSet, obj types.Object) string {
+	pos := fset.Position(obj.Pos())
+	return fmt.Sprintf("%s:%d:%d:%s", pos.Filename, pos.Line, pos.Column, obj.Name())
+}
+
+// GoPositionInput locates an identifier in a Go source file.
+type GoPositionInput struct {
+	Path   string `json:"path" jsonschema_description:"The relative path of the Go file containing the identifier."`
+	Line   int    `json:"line" jsonschema:"minimum=1" jsonschema_description:"The 1-based line number on which the identifier appears."`
+	Symbol string `json:"symbol" jsonschema_description:"The identifier to look up, exactly as written on that line (e.g. 'NewAgent' or 'Run')."`
+	Column int    `json:"column,omitempty" jsonschema:"minimum=1" jsonschema_description:"Optional 1-based column of the identifier, only needed if it appears more than once on the line."`
+}
+
+// findIdentifier loads the package containing the file in input and returns
+// the identifier it points at together with its package.
+func findIdentifier(input GoPositionInput) (*packages.Package, *ast.Ident, error) {
+	path, err := goPositionPath(input)
+	if err != nil {
+		return nil, nil, err
+	}
+	pkgs, err := loadWorkspaceGoPackages("file=" + path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return findIdentifierIn(pkgs, path, input)
+}
+
+// goPositionPath validates input and returns the absolute path of its file,
+// which must be in the workspace.
+func goPositionPath(input GoPositionInput) (string, error) {
+	if input.Path == "" || input.Line <= 0 || input.Symbol == "" {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+	_, rel, err := resolveInWorkspace(input.Path)
+	if err != nil {
+		return "", err
+	}
+	// Loaded file names are under the workspace root with its symlinks
+	// resolved.
+	return filepath.Join(WorkspaceRoot(), rel), nil
+}
+
+// findIdentifierIn returns the identifier input points at in the file at
+// the absolute path abs, together with the loaded package containing it.
+func findIdentifierIn(pkgs []*packages.Package, abs string, input GoPositionInput) (*packages.Package, *ast.Ident, error) {
+	for _, pkg := range pkgs {
+		for _, file := range pkg.Syntax {
+			if pkg.Fset.Position(file.Pos()).Filename != abs {
+				continue
+			}
+			var found *ast.Ident
+			ast.Inspect(file, func(n ast.Node) bool {
+				id, ok := n.(*ast.Ident)
+				if !ok || found != nil || id.Name != input.Symbol {
+					return found == nil
+				}
+				pos := pkg.Fset.Position(id.Pos())
+				if pos.Line == input.Line && (input.Column == 0 || pos.Column == input.Column) {
+					found = id
+				}
+				return false
+			})
+			if found != nil {
+				return pkg, found, nil
+			}
+		}
+	}
+	return nil, nil, fmt.Errorf("identifier %q not found on line %d of %s", input.Symbol, input.Line, input.Path)
+}
+
+// objectOf returns the object an identifier defines or refers to.
+func objectOf(pkg *packages.Package, id *ast.Ident) types.Object {
+	if obj := pkg.TypesInfo.Defs[id]; obj != nil {
+		return obj
+	}
+	return pkg.TypesInfo.Uses[id]
+}
+
+// describeObject renders an object's declaration with package-relative qualification.
+func describeObject(obj types.Object) string {
+	return types.ObjectString(obj, func(p *types.Package) string {
+		if obj.Pkg() != nil && p.Path() == obj.Pkg().Path() {
+			return ""
+		}
+		return p.Name()
+	})
+}
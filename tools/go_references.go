@@ -0,0 +1,92 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"os"
+	"sort"
+	"strings"
+)
+
+func GoReferences(input json.RawMessage) (string, error) {
+	goPositionInput := GoPositionInput{}
+	err := json.Unmarshal(input, &goPositionInput)
+	if err != nil {
+		return "", err
+	}
+
+	path, err := goPositionPath(goPositionInput)
+	if err != nil {
+		return "", err
+	}
+	pkgs, err := loadWorkspaceGoPackages("./...")
+	if err != nil {
+		return "", err
+	}
+	pkg, id, err := findIdentifierIn(pkgs, path, goPositionInput)
+	if err != nil {
+		return "", err
+	}
+	target := objectOf(pkg, id)
+	if target == nil {
+		return "", fmt.Errorf("no type information for %q; the package may not compile", id.Name)
+	}
+	if !target.Pos().IsValid() {
+		return "", fmt.Errorf("%s is predeclared by the language and has no references to list", target.Name())
+	}
+	key := objectKey(pkg.Fset, target)
+
+	// Test variants of a package repeat its files, so deduplicate positions.
+	seen := make(map[string]bool)
+	var positions []token.Position
+	for _, p := range pkgs {
+		for ident, obj := range p.TypesInfo.Uses {
+			if obj == nil || obj.Name() != target.Name() || objectKey(p.Fset, obj) != key {
+				continue
+			}
+			pos := p.Fset.Position(ident.Pos())
+			if s := pos.String(); !seen[s] {
+				seen[s] = true
+				positions = append(positions, pos)
+			}
+		}
+	}
+	if len(positions) == 0 {
+		return fmt.Sprintf("No references to %s found in the workspace", describeObject(target)), nil
+	}
+
+	sort.Slice(positions, func(i, j int) bool {
+		if positions[i].Filename != positions[j].Filename {
+			return positions[i].Filename < positions[j].Filename
+		}
+		return positions[i].Offset < positions[j].Offset
+	})
+
+	var result strings.Builder
+	fmt.Fprintf(&result, "%d references to %s:\n", len(positions), describeObject(target))
+	lines := make(map[string][]string)
+	for _, pos := range positions {
+		if _, ok := lines[pos.Filename]; !ok {
+			if content, err := os.ReadFile(pos.Filename); err == nil {
+				lines[pos.Filename] = strings.Split(string(content), "\n")
+			}
+		}
+		text := ""
+		if l := lines[pos.Filename]; pos.Line-1 < len(l) {
+			text = strings.TrimSpace(l[pos.Line-1])
+		}
+		fmt.Fprintf(&result, "%s: %s\n", formatPosition(pos), text)
+	}
+	return result.String(), nil
+}
+
+var GoReferencesDefinition = ToolDefinition{
+	Name: "go_references",
+	Description: `Find every use of a Go identifier across all packages in the workspace module, using the type checker rather than text search.
+Give the file, the line and the identifier as written on that line (either a declaration or a use). Returns file:line:column locations with the source line of each reference.
+`,
+	InputSchema: GenerateSchema[GoPositionInput](),
+	Function:    GoReferences,
+	ReadOnly:    true,
+}
@@ -0,0 +1,97 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"go/token"
+	"go/types"
+	"sort"
+	"strings"
+)
+
+type GoSymbolsInput struct {
+	Package string `json:"package,omitempty" jsonschema_description:"Optional package pattern, e.g. './tools', './...' or an import path. Defaults to the package in the current directory."`
+}
+
+// goSymbol is a single declaration listed by go_symbols.
+type goSymbol struct {
+	pos     token.Position
+	decl    string
+	methods []goSymbol // of a type
+}
+
+// sortByPosition sorts symbols by file and line.
+func sortByPosition(symbols []goSymbol) {
+	sort.SliceStable(symbols, func(i, j int) bool {
+		if symbols[i].pos.Filename != symbols[j].pos.Filename {
+			return symbols[i].pos.Filename < symbols[j].pos.Filename
+		}
+		return symbols[i].pos.Line < symbols[j].pos.Line
+	})
+}
+
+func GoSymbols(input json.RawMessage) (string, error) {
+	goSymbolsInput := GoSymbolsInput{}
+	err := json.Unmarshal(input, &goSymbolsInput)
+	if err != nil {
+		return "", err
+	}
+	pattern := goSymbolsInput.Package
+	if pattern == "" {
+		pattern = "."
+	}
+
+	pkgs, err := loadGoPackages(goTypesLoadMode, false, pattern)
+	if err != nil {
+		return "", err
+	}
+
+	var result strings.Builder
+	for _, pkg := range pkgs {
+		if pkg.Types == nil {
+			continue
+		}
+		var symbols []goSymbol
+		scope := pkg.Types.Scope()
+		for _, name := range scope.Names() {
+			obj := scope.Lookup(name)
+			symbol := goSymbol{pos: pkg.Fset.Position(obj.Pos()), decl: describeObject(obj)}
+			if named, ok := obj.Type().(*types.Named); ok {
+				if _, isType := obj.(*types.TypeName); isType {
+					for i := 0; i < named.NumMethods(); i++ {
+						method := named.Method(i)
+						symbol.methods = append(symbol.methods, goSymbol{pos: pkg.Fset.Position(method.Pos()), decl: "  " + describeObject(method)})
+					}
+					sortByPosition(symbol.methods)
+				}
+			}
+			symbols = append(symbols, symbol)
+		}
+		sortByPosition(symbols)
+
+		fmt.Fprintf(&result, "package %s (%s)\n", pkg.Name, pkg.PkgPath)
+		for _, err := range pkg.Errors {
+			fmt.Fprintf(&result, "error: %s\n", err)
+		}
+		// Methods are listed directly after the type that declares them,
+		// wherever they are declared.
+		for _, sym := range symbols {
+			fmt.Fprintf(&result, "%s: %s\n", formatPosition(sym.pos), sym.decl)
+			for _, method := range sym.methods {
+				fmt.Fprintf(&result, "%s: %s\n", formatPosition(method.pos), method.decl)
+			}
+		}
+		result.WriteString("\n")
+	}
+	return result.String(), nil
+}
+
+var GoSymbolsDefinition = ToolDefinition{
+	Name: "go_symbols",
+	Description: `List the top-level declarations (constants, variables, types, functions and methods) of one or more Go packages, with their file:line:column and full signatures.
+Use this to get an overview of a package's API before reading individual files.
+`,
+	InputSchema: GenerateSchema[GoSymbolsInput](),
+	Function:    GoSymbols,
+	ReadOnly:    true,
+}
@@ -14,7 +14,7 @@ func ListFiles(input json.RawMessage) (string, error) {
 	listFilesInput := ListFilesInput{}
 	err := json.Unmarshal(input, &listFilesInput)
 	if err != nil {
-		panic(err)
+		return "", err
 	}
 	dir := "."
 	if listFilesInput.Path != "" {
@@ -58,4 +58,5 @@ var ListFilesDefinition = ToolDefinition{
 	Description: "List files and directories at a given path. If no path is provided, lists files in the current directory.",
 	InputSchema: GenerateSchema[ListFilesInput](),
 	Function:    ListFiles,
+	ReadOnly:    true,
 }
@@ -0,0 +1,51 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+type MakeDirInput struct {
+	Path string `json:"path" jsonschema_description:"The relative path of the directory to create. Missing parent directories are created too."`
+}
+
+func MakeDir(input json.RawMessage) (string, error) {
+	makeDirInput := MakeDirInput{}
+	err := json.Unmarshal(input, &makeDirInput)
+	if err != nil {
+		return "", err
+	}
+
+	if makeDirInput.Path == "" {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+
+	path, rel, err := resolveInWorkspace(makeDirInput.Path)
+	if err != nil {
+		return "", err
+	}
+	createdDir := firstMissingDir(path)
+	if createdDir == "" {
+		return fmt.Sprintf("Directory %s already exists", rel), nil
+	}
+	if err := checkPermission("mkdir", rel); err != nil {
+		return "", err
+	}
+
+	if err := os.MkdirAll(path, 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+	pushCheckpoint("create directory "+rel, func() error {
+		return removeEmptyDirs(path, createdDir)
+	})
+
+	return fmt.Sprintf("Created directory %s", rel), nil
+}
+
+var MakeDirDefinition = ToolDefinition{
+	Name:        "make_dir",
+	Description: "Create a directory, including any missing parents, within the workspace. Paths outside the workspace and inside .git are refused. The change can be reverted with undo_last_change.",
+	InputSchema: GenerateSchema[MakeDirInput](),
+	Function:    MakeDir,
+}
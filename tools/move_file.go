@@ -0,0 +1,83 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+type MoveFileInput struct {
+	Source      string `json:"source" jsonschema_description:"The relative path of the file or directory to move."`
+	Destination string `json:"destination" jsonschema_description:"The relative path to move it to. Must not already exist; missing parent directories are created."`
+}
+
+func MoveFile(input json.RawMessage) (string, error) {
+	moveFileInput := MoveFileInput{}
+	err := json.Unmarshal(input, &moveFileInput)
+	if err != nil {
+		return "", err
+	}
+
+	if moveFileInput.Source == "" || moveFileInput.Destination == "" {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+
+	src, srcRel, err := resolveInWorkspace(moveFileInput.Source)
+	if err != nil {
+		return "", err
+	}
+	dst, dstRel, err := resolveInWorkspace(moveFileInput.Destination)
+	if err != nil {
+		return "", err
+	}
+	if srcRel == "." {
+		return "", fmt.Errorf("cannot move the workspace root")
+	}
+	if _, err := os.Lstat(src); err != nil {
+		return "", err
+	}
+	if _, err := os.Lstat(dst); err == nil {
+		return "", fmt.Errorf("%s already exists", moveFileInput.Destination)
+	}
+	if err := checkPermission("move", srcRel); err != nil {
+		return "", err
+	}
+	if err := checkPermission("move", dstRel); err != nil {
+		return "", err
+	}
+
+	createdDir := firstMissingDir(filepath.Dir(dst))
+	if err := os.MkdirAll(filepath.Dir(dst), 0755); err != nil {
+		return "", fmt.Errorf("failed to create directory: %w", err)
+	}
+	if err := os.Rename(src, dst); err != nil {
+		return "", err
+	}
+	tracker.forget(src)
+	noteChanged(src)
+	noteChanged(dst)
+
+	pushCheckpoint(fmt.Sprintf("move %s to %s", srcRel, dstRel), func() error {
+		tracker.forget(dst)
+		if err := os.Rename(dst, src); err != nil {
+			return err
+		}
+		if createdDir != "" {
+			return removeEmptyDirs(filepath.Dir(dst), createdDir)
+		}
+		return nil
+	})
+
+	return fmt.Sprintf("Moved %s to %s", srcRel, dstRel), nil
+}
+
+var MoveFileDefinition = ToolDefinition{
+	Name: "move_file",
+	Description: `Move or rename a file or directory within the workspace.
+Fails if the destination already exists. Paths outside the workspace and inside .git are refused.
+The move can be reverted with undo_last_change.
+`,
+	InputSchema: GenerateSchema[MoveFileInput](),
+	Function:    MoveFile,
+}
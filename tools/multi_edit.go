@@ -0,0 +1,78 @@
+package tools
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+)
+
+type MultiEditOperation struct {
+	OldStr string `json:"old_str" jsonschema_description:"Text to search for - must match exactly and must only have one match exactly"`
+	NewStr string `json:"new_str" jsonschema_description:"Text to replace old_str with"`
+}
+
+type MultiEditInput struct {
+	Path  string               `json:"path" jsonschema_description:"The path to the file"`
+	Edits []MultiEditOperation `json:"edits" jsonschema_description:"Replacements to apply in order. Each edit sees the result of the previous ones."`
+}
+
+func MultiEdit(input json.RawMessage) (string, error) {
+	multiEditInput := MultiEditInput{}
+	err := json.Unmarshal(input, &multiEditInput)
+	if err != nil {
+		return "", err
+	}
+
+	if multiEditInput.Path == "" || len(multiEditInput.Edits) == 0 {
+		return "", fmt.Errorf("invalid input parameters")
+	}
+
+	if _, _, err := resolveInWorkspace(multiEditInput.Path); err != nil {
+		return "", err
+	}
+
+	content, err := os.ReadFile(multiEditInput.Path)
+	if err != nil {
+		return "", err
+	}
+	if err := tracker.checkFresh(multiEditInput.Path, content); err != nil {
+		return "", err
+	}
+
+	oldContent := normalizeNewlines(string(content))
+	newContent := oldContent
+	for i, edit := range multiEditInput.Edits {
+		edit.OldStr = normalizeNewlines(edit.OldStr)
+		edit.NewStr = normalizeNewlines(edit.NewStr)
+		if edit.OldStr == "" || edit.OldStr == edit.NewStr {
+			return "", fmt.Errorf("edit %d: old_str must be non-empty and differ from new_str", i+1)
+		}
+		switch count := strings.Count(newContent, edit.OldStr); count {
+		case 0:
+			return "", fmt.Errorf("edit %d: old_str not found in file; no edits were applied", i+1)
+		case 1:
+			newContent = strings.Replace(newContent, edit.OldStr, edit.NewStr, 1)
+		default:
+			return "", fmt.Errorf("edit %d: old_str matches %d times, it must be unique; no edits were applied", i+1, count)
+		}
+	}
+
+	written, note, err := writeFile(multiEditInput.Path, newContent)
+	if err != nil {
+		return "", err
+	}
+
+	return diffResult(multiEditInput.Path, oldContent, written) + note, nil
+}
+
+var MultiEditDefinition = ToolDefinition{
+	Name: "multi_edit",
+	Description: `Make several edits to a single text file in one call.
+Applies each 'old_str' -> 'new_str' replacement in order. Every 'old_str' must match exactly once in the file as it is after the previous edits.
+The file must have been read with read_file first. The edits are atomic: if any edit fails, none are applied and the file is left untouched.
+Prefer this over repeated edit_file calls when changing several places in the same file. Returns a unified diff of the change.
+`,
+	InputSchema: GenerateSchema[MultiEditInput](),
+	Function:    MultiEdit,
+}
@@ -0,0 +1,29 @@
+package tools
+
+import "path/filepath"
+
+// PermissionFunc decides whether a filesystem access may proceed. Action is
+// one of "read", "write", "move", "delete" or "mkdir", and path is relative
+// to the workspace root. A non-nil error blocks the action and is shown to the model.
+type PermissionFunc func(action, path string) error
+
+var permissionGate PermissionFunc
+
+// SetPermissionGate installs the function consulted before tools read or
+// modify the filesystem.
+func SetPermissionGate(gate PermissionFunc) {
+	permissionGate = gate
+}
+
+// checkPermission consults the permission gate, if any, for action on path.
+func checkPermission(action, path string) error {
+	if permissionGate == nil {
+		return nil
+	}
+	if abs, err := filepath.Abs(path); err == nil {
+		if rel, err := filepath.Rel(WorkspaceRoot(), abs); err == nil {
+			path = rel
+		}
+	}
+	return permissionGate(action, path)
+}
@@ -2,30 +2,71 @@ package tools
 
 import (
 	"encoding/json"
+	"fmt"
 	"os"
+	"strings"
 )
 
 type ReadFileInput struct {
-	Path string `json:"path" jsonschema_description:"The relative path of a file in the working directory."`
+	Path   string `json:"path" jsonschema_description:"The relative path of a file in the working directory."`
+	Offset int    `json:"offset,omitempty" jsonschema:"minimum=1" jsonschema_description:"Optional 1-based line number to start reading from. Use with limit to page through large files."`
+	Limit  int    `json:"limit,omitempty" jsonschema:"minimum=1" jsonschema_description:"Optional maximum number of lines to return."`
 }
 
 func ReadFile(input json.RawMessage) (string, error) {
 	readFileInput := ReadFileInput{}
 	err := json.Unmarshal(input, &readFileInput)
 	if err != nil {
-		panic(err)
+		return "", err
 	}
 
+	if err := checkPermission("read", readFileInput.Path); err != nil {
+		return "", err
+	}
 	content, err := os.ReadFile(readFileInput.Path)
 	if err != nil {
 		return "", err
 	}
-	return string(content), nil
+	tracker.record(readFileInput.Path, content)
+	if readFileInput.Offset <= 1 && readFileInput.Limit <= 0 {
+		return string(content), nil
+	}
+	return lineRange(string(content), readFileInput.Offset, readFileInput.Limit)
+}
+
+// lineRange returns limit lines of content starting at the 1-based line
+// offset, followed by a note saying which lines were shown.
+func lineRange(content string, offset, limit int) (string, error) {
+	lines := strings.SplitAfter(content, "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if offset < 1 {
+		offset = 1
+	}
+	if offset > len(lines) {
+		return "", fmt.Errorf("offset %d is past the end of the file, which has %d lines", offset, len(lines))
+	}
+	end := len(lines)
+	if limit > 0 && offset-1+limit < end {
+		end = offset - 1 + limit
+	}
+
+	result := strings.Join(lines[offset-1:end], "")
+	if !strings.HasSuffix(result, "\n") {
+		result += "\n"
+	}
+	result += fmt.Sprintf("[Lines %d-%d of %d.", offset, end, len(lines))
+	if end < len(lines) {
+		result += fmt.Sprintf(" Continue with offset %d.", end+1)
+	}
+	return result + "]", nil
 }
 
 var ReadFileDefinition = ToolDefinition{
 	Name:        "read_file",
-	Description: "Read the contents of a given relative file path. Use this when you want to see what's inside a file. Do not use this with directory names.",
+	Description: "Read the contents of a given relative file path. Use this when you want to see what's inside a file. Do not use this with directory names. For large files, use offset and limit to read a range of lines.",
 	InputSchema: GenerateSchema[ReadFileInput](),
 	Function:    ReadFile,
+	ReadOnly:    true,
 }
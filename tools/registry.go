@@ -0,0 +1,105 @@
+package tools
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"sync"
+)
+
+// toolNameRe matches the tool names accepted by the Anthropic API.
+var toolNameRe = regexp.MustCompile(`^[a-zA-Z0-9_-]{1,64}$`)
+
+// BuiltinDefinitions returns the tools compiled into the binary.
+func BuiltinDefinitions() []ToolDefinition {
+	return []ToolDefinition{
+		ReadFileDefinition,
+		EditFileDefinition,
+		MultiEditDefinition,
+		ApplyPatchDefinition,
+		ListFilesDefinition,
+		MoveFileDefinition,
+		DeleteFileDefinition,
+		MakeDirDefinition,
+		UndoDefinition,
+		GoDefinitionDefinition,
+		GoReferencesDefinition,
+		GoSymbolsDefinition,
+		GoDocDefinition,
+		GoCheckDefinition,
+	}
+}
+
+// Registry is the set of tools offered to the model, keyed by name.
+type Registry struct {
+	mu    sync.Mutex
+	tools []ToolDefinition
+}
+
+// NewRegistry returns a registry holding defs. It panics on duplicate names,
+// which indicates a programming error in the built-in tool list.
+func NewRegistry(defs ...ToolDefinition) *Registry {
+	r := &Registry{}
+	for _, def := range defs {
+		if err := r.Register(def); err != nil {
+			panic(err)
+		}
+	}
+	return r
+}
+
+// Register adds a tool, rejecting invalid or duplicate names.
+func (r *Registry) Register(def ToolDefinition) error {
+	if !toolNameRe.MatchString(def.Name) {
+		return fmt.Errorf("invalid tool name %q: use 1-64 letters, digits, '_' or '-'", def.Name)
+	}
+	if def.Function == nil {
+		return fmt.Errorf("tool %s has no function", def.Name)
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, existing := range r.tools {
+		if existing.Name == def.Name {
+			return fmt.Errorf("tool %s is already registered", def.Name)
+		}
+	}
+	r.tools = append(r.tools, def)
+	return nil
+}
+
+// Definitions returns the registered tools in registration order.
+func (r *Registry) Definitions() []ToolDefinition {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]ToolDefinition(nil), r.tools...)
+}
+
+// LoadDir registers an external tool for every *.json manifest in dir. A
+// missing directory is not an error. Manifests that fail to load are skipped
+// and reported together in the returned error; the rest are still registered.
+func (r *Registry) LoadDir(dir string) error {
+	if _, err := os.Stat(dir); os.IsNotExist(err) {
+		return nil
+	}
+	manifests, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(manifests)
+
+	var errs []error
+	for _, path := range manifests {
+		def, err := LoadExternalTool(path)
+		if err == nil {
+			err = r.Register(def)
+		}
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,78 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata")
+
+// TestSchemas compares the input schema of every built-in tool with its
+// golden file, so that changes to the input structs are reviewed as changes
+// to what the model sees. Run with -update to accept them.
+func TestSchemas(t *testing.T) {
+	dir := filepath.Join("testdata", "schemas")
+	seen := map[string]bool{}
+	for _, def := range BuiltinDefinitions() {
+		t.Run(def.Name, func(t *testing.T) {
+			if seen[def.Name] {
+				t.Fatalf("duplicate tool name %q", def.Name)
+			}
+			seen[def.Name] = true
+
+			// The schema as sent to the model, indented for review.
+			data, err := SchemaJSON(def.InputSchema)
+			if err != nil {
+				t.Fatalf("cannot marshal schema: %v", err)
+			}
+			var schema map[string]any
+			if err := json.Unmarshal(data, &schema); err != nil {
+				t.Fatalf("cannot unmarshal schema: %v", err)
+			}
+			if _, ok := schema["-"]; ok {
+				t.Errorf("schema has a \"-\" property: %s", data)
+			}
+			got, err := json.MarshalIndent(schema, "", "  ")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got = append(got, '\n')
+
+			golden := filepath.Join(dir, def.Name+".json")
+			if *update {
+				if err := os.MkdirAll(dir, 0o755); err != nil {
+					t.Fatal(err)
+				}
+				if err := os.WriteFile(golden, got, 0o644); err != nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			want, err := os.ReadFile(golden)
+			if err != nil {
+				t.Fatalf("cannot read golden file (run with -update to create it): %v", err)
+			}
+			if !bytes.Equal(got, want) {
+				t.Errorf("schema differs from %s (run with -update to accept it):\n%s", golden, got)
+			}
+		})
+	}
+
+	// Golden files left over from removed or renamed tools.
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	for _, file := range files {
+		name := filepath.Base(file)
+		name = name[:len(name)-len(".json")]
+		if !seen[name] {
+			if *update {
+				os.Remove(file)
+				continue
+			}
+			t.Errorf("golden file %s has no tool", file)
+		}
+	}
+}
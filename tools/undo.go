@@ -0,0 +1,16 @@
+package tools
+
+import "encoding/json"
+
+type UndoInput struct{}
+
+func Undo(input json.RawMessage) (string, error) {
+	return UndoLastChange()
+}
+
+var UndoDefinition = ToolDefinition{
+	Name:        "undo_last_change",
+	Description: "Revert the most recent file change made by a tool in this session (edit, create, move, delete or directory creation). Call it repeatedly to step further back. Re-read any affected file before editing it again.",
+	InputSchema: GenerateSchema[UndoInput](),
+	Function:    Undo,
+}
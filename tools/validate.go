@@ -0,0 +1,253 @@
+package tools
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/anthropics/anthropic-sdk-go"
+)
+
+// ValidationError lists every way a tool input violates the tool's schema.
+type ValidationError struct {
+	Problems []ValidationProblem
+}
+
+// ValidationProblem is a single schema violation. Path locates the offending
+// value, e.g. "edits[1].old_str"; it is empty for the input as a whole.
+type ValidationProblem struct {
+	Path    string `json:"path"`
+	Message string `json:"message"`
+}
+
+func (e *ValidationError) Error() string {
+	var out strings.Builder
+	out.WriteString("invalid tool input:")
+	for _, p := range e.Problems {
+		out.WriteString("\n  - ")
+		if p.Path != "" {
+			out.WriteString(p.Path + ": ")
+		}
+		out.WriteString(p.Message)
+	}
+	return out.String()
+}
+
+// ValidateInput checks input against schema. It supports the subset of JSON
+// schema emitted by GenerateSchema and commonly used by external tools: type,
+// properties, required, additionalProperties, items, enum, minimum, maximum,
+// minLength, maxLength, minItems, maxItems and pattern. Unknown keywords are
+// ignored. The returned error is a *ValidationError.
+func ValidateInput(schema anthropic.ToolInputSchemaParam, input json.RawMessage) error {
+	data, err := SchemaJSON(schema)
+	if err != nil {
+		return err
+	}
+	var schemaObject map[string]any
+	if err := json.Unmarshal(data, &schemaObject); err != nil {
+		return err
+	}
+
+	if len(bytes.TrimSpace(input)) == 0 {
+		input = json.RawMessage("{}")
+	}
+	decoder := json.NewDecoder(bytes.NewReader(input))
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return &ValidationError{Problems: []ValidationProblem{{Message: "input is not valid JSON: " + err.Error()}}}
+	}
+
+	var v validator
+	v.validate(schemaObject, value, "")
+	if len(v.problems) > 0 {
+		return &ValidationError{Problems: v.problems}
+	}
+	return nil
+}
+
+type validator struct {
+	problems []ValidationProblem
+}
+
+func (v *validator) fail(path, format string, args ...any) {
+	v.problems = append(v.problems, ValidationProblem{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) validate(schema map[string]any, value any, path string) {
+	if t, ok := schema["type"]; ok && !matchesType(t, value) {
+		v.fail(path, "expected %s, got %s", describeType(t), jsonType(value))
+		return
+	}
+	if enum, ok := schema["enum"].([]any); ok && !inEnum(enum, value) {
+		var options []string
+		for _, e := range enum {
+			data, _ := json.Marshal(e)
+			options = append(options, string(data))
+		}
+		v.fail(path, "must be one of %s", strings.Join(options, ", "))
+	}
+
+	switch value := value.(type) {
+	case map[string]any:
+		v.validateObject(schema, value, path)
+	case []any:
+		if min, ok := number(schema["minItems"]); ok && float64(len(value)) < min {
+			v.fail(path, "must have at least %v items", min)
+		}
+		if max, ok := number(schema["maxItems"]); ok && float64(len(value)) > max {
+			v.fail(path, "must have at most %v items", max)
+		}
+		if items, ok := schema["items"].(map[string]any); ok {
+			for i, item := range value {
+				v.validate(items, item, fmt.Sprintf("%s[%d]", path, i))
+			}
+		}
+	case string:
+		length := float64(utf8.RuneCountInString(value))
+		if min, ok := number(schema["minLength"]); ok && length < min {
+			v.fail(path, "must be at least %v characters long", min)
+		}
+		if max, ok := number(schema["maxLength"]); ok && length > max {
+			v.fail(path, "must be at most %v characters long", max)
+		}
+		if pattern, ok := schema["pattern"].(string); ok {
+			if re, err := regexp.Compile(pattern); err == nil && !re.MatchString(value) {
+				v.fail(path, "must match the pattern %q", pattern)
+			}
+		}
+	case json.Number:
+		n, _ := value.Float64()
+		if min, ok := number(schema["minimum"]); ok && n < min {
+			v.fail(path, "must be at least %v", min)
+		}
+		if max, ok := number(schema["maximum"]); ok && n > max {
+			v.fail(path, "must be at most %v", max)
+		}
+	}
+}
+
+func (v *validator) validateObject(schema map[string]any, value map[string]any, path string) {
+	properties, _ := schema["properties"].(map[string]any)
+	if required, ok := schema["required"].([]any); ok {
+		for _, name := range required {
+			name, _ := name.(string)
+			if _, ok := value[name]; !ok {
+				v.fail(joinPath(path, name), "required property is missing")
+			}
+		}
+	}
+
+	names := make([]string, 0, len(value))
+	for name := range value {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		if property, ok := properties[name].(map[string]any); ok {
+			v.validate(property, value[name], joinPath(path, name))
+			continue
+		}
+		switch additional := schema["additionalProperties"].(type) {
+		case bool:
+			if !additional {
+				known := make([]string, 0, len(properties))
+				for p := range properties {
+					known = append(known, p)
+				}
+				sort.Strings(known)
+				v.fail(joinPath(path, name), "unknown property; expected one of: %s", strings.Join(known, ", "))
+			}
+		case map[string]any:
+			v.validate(additional, value[name], joinPath(path, name))
+		}
+	}
+}
+
+func joinPath(path, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+// matchesType reports whether value has the JSON schema type t, which is a
+// type name or a list of them.
+func matchesType(t any, value any) bool {
+	switch t := t.(type) {
+	case string:
+		actual := jsonType(value)
+		return actual == t || (t == "number" && actual == "integer")
+	case []any:
+		for _, option := range t {
+			if matchesType(option, value) {
+				return true
+			}
+		}
+		return false
+	}
+	return true
+}
+
+func describeType(t any) string {
+	if list, ok := t.([]any); ok {
+		var names []string
+		for _, name := range list {
+			names = append(names, fmt.Sprint(name))
+		}
+		return strings.Join(names, " or ")
+	}
+	return fmt.Sprint(t)
+}
+
+// jsonType returns the JSON schema type name of a decoded value.
+func jsonType(value any) string {
+	switch value := value.(type) {
+	case nil:
+		return "null"
+	case bool:
+		return "boolean"
+	case string:
+		return "string"
+	case json.Number:
+		if _, err := value.Int64(); err == nil {
+			return "integer"
+		}
+		if f, err := value.Float64(); err == nil && f == float64(int64(f)) {
+			return "integer"
+		}
+		return "number"
+	case []any:
+		return "array"
+	case map[string]any:
+		return "object"
+	}
+	return fmt.Sprintf("%T", value)
+}
+
+func inEnum(enum []any, value any) bool {
+	data, _ := json.Marshal(value)
+	for _, option := range enum {
+		optionData, _ := json.Marshal(option)
+		if bytes.Equal(data, optionData) {
+			return true
+		}
+	}
+	return false
+}
+
+// number converts a schema keyword value to a float64.
+func number(value any) (float64, bool) {
+	switch value := value.(type) {
+	case float64:
+		return value, true
+	case json.Number:
+		f, err := value.Float64()
+		return f, err == nil
+	}
+	return 0, false
+}
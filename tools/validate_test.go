@@ -0,0 +1,84 @@
+package tools
+
+import (
+	"encoding/json"
+	"errors"
+	"testing"
+)
+
+func TestValidateInput(t *testing.T) {
+	schema, err := SchemaFromJSON(json.RawMessage(`{
+		"type": "object",
+		"properties": {
+			"path": {"type": "string", "minLength": 1},
+			"mode": {"type": "string", "enum": ["read", "write"]},
+			"count": {"type": "integer", "minimum": 1, "maximum": 10},
+			"edits": {
+				"type": "array",
+				"maxItems": 2,
+				"items": {
+					"type": "object",
+					"properties": {"old": {"type": "string"}, "new": {"type": "string"}},
+					"required": ["old"],
+					"additionalProperties": false
+				}
+			},
+			"options": {
+				"type": "object",
+				"properties": {"depth": {"type": ["integer", "null"]}}
+			}
+		},
+		"required": ["path"]
+	}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		input string
+		want  []ValidationProblem // nil if the input is valid
+	}{
+		{name: "valid", input: `{"path": "a.go", "mode": "read", "count": 3, "edits": [{"old": "x", "new": "y"}], "options": {"depth": null}}`},
+		{name: "empty input", input: ``, want: []ValidationProblem{{"path", "required property is missing"}}},
+		{name: "missing required", input: `{"mode": "read"}`, want: []ValidationProblem{{"path", "required property is missing"}}},
+		{name: "type mismatch", input: `{"path": 3}`, want: []ValidationProblem{{"path", "expected string, got integer"}}},
+		{name: "integer", input: `{"path": "a", "count": 1.5}`, want: []ValidationProblem{{"count", "expected integer, got number"}}},
+		{name: "not an object", input: `[1]`, want: []ValidationProblem{{"", "expected object, got array"}}},
+		{name: "enum", input: `{"path": "a", "mode": "delete"}`, want: []ValidationProblem{{"mode", `must be one of "read", "write"`}}},
+		{name: "bounds", input: `{"path": "", "count": 11}`, want: []ValidationProblem{
+			{"count", "must be at most 10"},
+			{"path", "must be at least 1 characters long"},
+		}},
+		{name: "nested object", input: `{"path": "a", "options": {"depth": "deep"}}`, want: []ValidationProblem{{"options.depth", "expected integer or null, got string"}}},
+		{name: "array items", input: `{"path": "a", "edits": [{"old": "x"}, {"new": "y", "extra": 1}]}`, want: []ValidationProblem{
+			{"edits[1].old", "required property is missing"},
+			{"edits[1].extra", "unknown property; expected one of: new, old"},
+		}},
+		{name: "array length", input: `{"path": "a", "edits": [{"old": "1"}, {"old": "2"}, {"old": "3"}]}`, want: []ValidationProblem{{"edits", "must have at most 2 items"}}},
+		{name: "invalid JSON", input: `{"path": `, want: []ValidationProblem{{"", "input is not valid JSON: unexpected EOF"}}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateInput(schema, json.RawMessage(tt.input))
+			if tt.want == nil {
+				if err != nil {
+					t.Errorf("ValidateInput: %v", err)
+				}
+				return
+			}
+			var verr *ValidationError
+			if !errors.As(err, &verr) {
+				t.Fatalf("ValidateInput: err = %v, want a *ValidationError", err)
+			}
+			if len(verr.Problems) != len(tt.want) {
+				t.Fatalf("problems = %+v, want %+v", verr.Problems, tt.want)
+			}
+			for i, p := range verr.Problems {
+				if p != tt.want[i] {
+					t.Errorf("problem %d = %+v, want %+v", i, p, tt.want[i])
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,83 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+var (
+	workspaceMu   sync.Mutex
+	workspaceRoot string
+)
+
+// SetWorkspaceRoot confines filesystem mutations to dir.
+func SetWorkspaceRoot(dir string) error {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return err
+	}
+	if resolved, err := filepath.EvalSymlinks(abs); err == nil {
+		abs = resolved
+	}
+
+	workspaceMu.Lock()
+	defer workspaceMu.Unlock()
+	workspaceRoot = abs
+	return nil
+}
+
+// WorkspaceRoot returns the directory tools are confined to, defaulting to
+// the current working directory.
+func WorkspaceRoot() string {
+	workspaceMu.Lock()
+	root := workspaceRoot
+	workspaceMu.Unlock()
+
+	if root == "" {
+		if wd, err := os.Getwd(); err == nil {
+			_ = SetWorkspaceRoot(wd)
+			return WorkspaceRoot()
+		}
+		return "."
+	}
+	return root
+}
+
+// resolveLink resolves symlinks in the longest existing prefix of path, so
+// that paths which do not exist yet are still checked against their real
+// parent directory.
+func resolveLink(path string) string {
+	rest := ""
+	for dir := path; ; dir = filepath.Dir(dir) {
+		if resolved, err := filepath.EvalSymlinks(dir); err == nil {
+			return filepath.Join(resolved, rest)
+		}
+		if filepath.Dir(dir) == dir {
+			return path
+		}
+		rest = filepath.Join(filepath.Base(dir), rest)
+	}
+}
+
+// resolveInWorkspace returns the absolute form of path, or an error if it
+// points outside the workspace root or into a .git directory.
+func resolveInWorkspace(path string) (string, string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	rel, err := filepath.Rel(WorkspaceRoot(), resolveLink(abs))
+	if err != nil || rel == ".." || strings.HasPrefix(rel, ".."+string(filepath.Separator)) {
+		return "", "", fmt.Errorf("%s is outside the workspace %s", path, WorkspaceRoot())
+	}
+	for _, part := range strings.Split(rel, string(filepath.Separator)) {
+		if part == ".git" {
+			return "", "", fmt.Errorf("%s is inside a .git directory, which tools may not modify", path)
+		}
+	}
+	return abs, rel, nil
+}
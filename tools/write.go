@@ -0,0 +1,130 @@
+package tools
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// normalizeNewlines converts CRLF line endings to LF.
+func normalizeNewlines(content string) string {
+	return strings.ReplaceAll(content, "\r\n", "\n")
+}
+
+// usesCRLF reports whether most lines in content end with CRLF.
+func usesCRLF(content string) bool {
+	crlf := strings.Count(content, "\r\n")
+	return crlf > 0 && crlf*2 >= strings.Count(content, "\n")
+}
+
+// matchLineEndings rewrites updated to use the same line-ending style and
+// trailing-newline convention as original.
+func matchLineEndings(original, updated string) string {
+	updated = normalizeNewlines(updated)
+	if original != "" && updated != "" {
+		hadNewline := strings.HasSuffix(original, "\n")
+		hasNewline := strings.HasSuffix(updated, "\n")
+		if hadNewline && !hasNewline {
+			updated += "\n"
+		} else if !hadNewline && hasNewline {
+			updated = strings.TrimSuffix(updated, "\n")
+		}
+	}
+	if usesCRLF(original) {
+		updated = strings.ReplaceAll(updated, "\n", "\r\n")
+	}
+	return updated
+}
+
+// writeFile replaces the content of path atomically by writing a temporary
+// file in the same directory and renaming it into place. The content is first
+// passed through the post-write hooks. The mode, owner, line-ending style and
+// trailing newline of an existing file are preserved, and the write is
+// refused unless the agent has read the current version, and for paths
+// outside the workspace or inside .git.
+//
+// It returns the content as written, with LF line endings, together with a
+// note for the model showing what the hooks changed, or why they failed.
+func writeFile(path, content string) (string, string, error) {
+	mode := os.FileMode(0644)
+	var info os.FileInfo
+
+	if _, _, err := resolveInWorkspace(path); err != nil {
+		return "", "", err
+	}
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		path = resolved
+	}
+
+	if err := checkPermission("write", path); err != nil {
+		return "", "", err
+	}
+
+	existing, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := tracker.checkFresh(path, existing); err != nil {
+			return "", "", err
+		}
+		info, err = os.Stat(path)
+		if err != nil {
+			return "", "", err
+		}
+		mode = info.Mode().Perm()
+	case !os.IsNotExist(err):
+		return "", "", err
+	}
+
+	requested := normalizeNewlines(content)
+	formatted, hookErrs := runPostWriteHooks(path, requested)
+	note := ""
+	if formatted != requested {
+		note = "\nThe file was reformatted automatically after this change:\n" + unifiedDiff(path, requested, formatted)
+	}
+	for _, hookErr := range hookErrs {
+		note += "\nWarning: " + hookErr.Error()
+	}
+	onDisk := formatted
+	if info != nil {
+		onDisk = matchLineEndings(string(existing), formatted)
+	}
+
+	restore, err := backupFile(path)
+	if err != nil {
+		return "", "", err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), "."+filepath.Base(path)+".tmp*")
+	if err != nil {
+		return "", "", fmt.Errorf("failed to create temporary file: %w", err)
+	}
+	tmpPath := tmp.Name()
+	defer os.Remove(tmpPath) // no-op once the rename has succeeded
+
+	if _, err := tmp.WriteString(onDisk); err != nil {
+		tmp.Close()
+		return "", "", err
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return "", "", err
+	}
+	if err := tmp.Close(); err != nil {
+		return "", "", err
+	}
+	if err := os.Chmod(tmpPath, mode); err != nil {
+		return "", "", err
+	}
+	if info != nil {
+		preserveOwner(tmpPath, info)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return "", "", err
+	}
+
+	tracker.record(path, []byte(onDisk))
+	noteChanged(path)
+	pushCheckpoint("write "+path, restore)
+	return normalizeNewlines(onDisk), note, nil
+}
@@ -0,0 +1,151 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// MemoryExporter keeps spans in memory, for inspecting them in tests.
+type MemoryExporter struct {
+	mu    sync.Mutex
+	spans []SpanData
+}
+
+// ExportSpan records span.
+func (e *MemoryExporter) ExportSpan(span SpanData) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = append(e.spans, span)
+}
+
+// Spans returns the spans recorded so far, in the order they ended.
+func (e *MemoryExporter) Spans() []SpanData {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]SpanData(nil), e.spans...)
+}
+
+// Reset discards the recorded spans.
+func (e *MemoryExporter) Reset() {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.spans = nil
+}
+
+// Shutdown does nothing.
+func (e *MemoryExporter) Shutdown(ctx context.Context) error {
+	return nil
+}
+
+// FileExporter writes spans to a file in the Chrome trace event format,
+// which chrome://tracing and https://ui.perfetto.dev open. The file is a
+// JSON array that is closed on Shutdown; viewers also accept it unclosed,
+// should the program exit without shutting down.
+type FileExporter struct {
+	mu    sync.Mutex
+	file  *os.File
+	w     *bufio.Writer
+	count int
+}
+
+// NewFileExporter creates the trace file at path, and its directory.
+func NewFileExporter(path string) (*FileExporter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return nil, fmt.Errorf("failed to create trace directory: %v", err)
+	}
+	file, err := os.Create(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create trace file: %v", err)
+	}
+	e := &FileExporter{file: file, w: bufio.NewWriter(file)}
+	e.w.WriteString("[\n")
+	return e, nil
+}
+
+// traceEvent is a complete ("X") event of the Chrome trace event format.
+// Times are in microseconds.
+type traceEvent struct {
+	Name      string         `json:"name"`
+	Category  string         `json:"cat"`
+	Phase     string         `json:"ph"`
+	Timestamp int64          `json:"ts"`
+	Duration  int64          `json:"dur"`
+	PID       int            `json:"pid"`
+	TID       int            `json:"tid"`
+	Args      map[string]any `json:"args,omitempty"`
+}
+
+// ExportSpan appends span to the file. Each span is flushed, so the file
+// stays useful if the program is killed.
+func (e *FileExporter) ExportSpan(span SpanData) {
+	args := map[string]any{"trace_id": span.TraceID, "span_id": span.SpanID}
+	if span.ParentID != "" {
+		args["parent_id"] = span.ParentID
+	}
+	for _, attr := range span.Attrs {
+		args[attr.Key] = jsonValue(attr.Value)
+	}
+	if span.Err != "" {
+		args["error"] = span.Err
+	}
+	data, err := json.Marshal(traceEvent{
+		Name:      span.Name,
+		Category:  "agent",
+		Phase:     "X",
+		Timestamp: span.Start.UnixMicro(),
+		Duration:  span.End.Sub(span.Start).Microseconds(),
+		PID:       1,
+		TID:       span.Lane,
+		Args:      args,
+	})
+	if err != nil {
+		return
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.file == nil {
+		return
+	}
+	if e.count > 0 {
+		e.w.WriteString(",\n")
+	}
+	e.count++
+	e.w.Write(data)
+	e.w.Flush()
+}
+
+// Shutdown closes the JSON array and the file.
+func (e *FileExporter) Shutdown(ctx context.Context) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	if e.file == nil {
+		return nil
+	}
+	e.w.WriteString("\n]\n")
+	err := e.w.Flush()
+	if closeErr := e.file.Close(); err == nil {
+		err = closeErr
+	}
+	e.file = nil
+	return err
+}
+
+// jsonValue returns v if encoding/json can represent it, and its fmt
+// representation otherwise.
+func jsonValue(v any) any {
+	switch v := v.(type) {
+	case string, bool, int, int32, int64, uint, uint32, uint64, float32, float64:
+		return v
+	case fmt.Stringer:
+		return v.String()
+	case error:
+		return v.Error()
+	}
+	return fmt.Sprint(v)
+}
@@ -0,0 +1,249 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// otlpBatchSize and otlpInterval bound how long spans wait to be sent.
+	otlpBatchSize = 128
+	otlpInterval  = 5 * time.Second
+	// otlpQueueSize bounds the spans buffered while the collector is slow;
+	// beyond it spans are dropped rather than blocking the agent.
+	otlpQueueSize = 4096
+)
+
+// OTLPExporter sends spans in batches to an OpenTelemetry collector using
+// OTLP over HTTP with JSON encoding.
+type OTLPExporter struct {
+	url         string
+	headers     map[string]string
+	serviceName string
+	client      *http.Client
+
+	spans   chan SpanData
+	flush   chan chan error
+	done    chan struct{}
+	stopped sync.Once
+
+	// OnError, if set, is called when a batch cannot be delivered.
+	OnError func(error)
+}
+
+// NewOTLPExporter returns an exporter for the collector at endpoint, such as
+// http://localhost:4318; spans are posted to its /v1/traces path unless
+// endpoint already names it. Headers are sent with every request.
+func NewOTLPExporter(endpoint string, headers map[string]string, serviceName string) *OTLPExporter {
+	url := strings.TrimRight(endpoint, "/")
+	if !strings.HasSuffix(url, "/v1/traces") {
+		url += "/v1/traces"
+	}
+	e := &OTLPExporter{
+		url:         url,
+		headers:     headers,
+		serviceName: serviceName,
+		client:      &http.Client{Timeout: 10 * time.Second},
+		spans:       make(chan SpanData, otlpQueueSize),
+		flush:       make(chan chan error),
+		done:        make(chan struct{}),
+	}
+	go e.run()
+	return e
+}
+
+// ExportSpan queues span for sending.
+func (e *OTLPExporter) ExportSpan(span SpanData) {
+	select {
+	case e.spans <- span:
+	default:
+	}
+}
+
+// Shutdown sends the queued spans and stops the exporter.
+func (e *OTLPExporter) Shutdown(ctx context.Context) error {
+	var err error
+	e.stopped.Do(func() {
+		reply := make(chan error, 1)
+		select {
+		case e.flush <- reply:
+			select {
+			case err = <-reply:
+			case <-ctx.Done():
+				err = ctx.Err()
+			}
+		case <-ctx.Done():
+			err = ctx.Err()
+		}
+		close(e.done)
+	})
+	return err
+}
+
+// run batches queued spans and sends them when the batch is full, on every
+// tick, and when flushing.
+func (e *OTLPExporter) run() {
+	ticker := time.NewTicker(otlpInterval)
+	defer ticker.Stop()
+	var batch []SpanData
+	send := func() error {
+		if len(batch) == 0 {
+			return nil
+		}
+		err := e.send(batch)
+		batch = nil
+		if err != nil && e.OnError != nil {
+			e.OnError(err)
+		}
+		return err
+	}
+	for {
+		select {
+		case span := <-e.spans:
+			batch = append(batch, span)
+			if len(batch) >= otlpBatchSize {
+				send()
+			}
+		case <-ticker.C:
+			send()
+		case reply := <-e.flush:
+			for drained := false; !drained; {
+				select {
+				case span := <-e.spans:
+					batch = append(batch, span)
+				default:
+					drained = true
+				}
+			}
+			reply <- send()
+		case <-e.done:
+			return
+		}
+	}
+}
+
+// send posts a batch of spans.
+func (e *OTLPExporter) send(batch []SpanData) error {
+	body, err := json.Marshal(e.request(batch))
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest(http.MethodPost, e.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	for key, value := range e.headers {
+		req.Header.Set(key, value)
+	}
+	resp, err := e.client.Do(req)
+	if err != nil {
+		return fmt.Errorf("failed to export spans: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		msg, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		return fmt.Errorf("failed to export spans: %s: %s", resp.Status, strings.TrimSpace(string(msg)))
+	}
+	return nil
+}
+
+// The OTLP JSON encoding of an ExportTraceServiceRequest. IDs are hex and
+// 64-bit integers are strings.
+type (
+	otlpRequest struct {
+		ResourceSpans []otlpResourceSpans `json:"resourceSpans"`
+	}
+	otlpResourceSpans struct {
+		Resource   otlpResource     `json:"resource"`
+		ScopeSpans []otlpScopeSpans `json:"scopeSpans"`
+	}
+	otlpResource struct {
+		Attributes []otlpKeyValue `json:"attributes"`
+	}
+	otlpScopeSpans struct {
+		Scope otlpScope  `json:"scope"`
+		Spans []otlpSpan `json:"spans"`
+	}
+	otlpScope struct {
+		Name string `json:"name"`
+	}
+	otlpSpan struct {
+		TraceID           string         `json:"traceId"`
+		SpanID            string         `json:"spanId"`
+		ParentSpanID      string         `json:"parentSpanId,omitempty"`
+		Name              string         `json:"name"`
+		Kind              int            `json:"kind"`
+		StartTimeUnixNano string         `json:"startTimeUnixNano"`
+		EndTimeUnixNano   string         `json:"endTimeUnixNano"`
+		Attributes        []otlpKeyValue `json:"attributes,omitempty"`
+		Status            otlpStatus     `json:"status"`
+	}
+	otlpStatus struct {
+		Code    int    `json:"code,omitempty"`
+		Message string `json:"message,omitempty"`
+	}
+	otlpKeyValue struct {
+		Key   string         `json:"key"`
+		Value map[string]any `json:"value"`
+	}
+)
+
+const (
+	otlpSpanKindInternal = 1
+	otlpStatusError      = 2
+)
+
+func (e *OTLPExporter) request(batch []SpanData) otlpRequest {
+	spans := make([]otlpSpan, 0, len(batch))
+	for _, s := range batch {
+		span := otlpSpan{
+			TraceID:           s.TraceID,
+			SpanID:            s.SpanID,
+			ParentSpanID:      s.ParentID,
+			Name:              s.Name,
+			Kind:              otlpSpanKindInternal,
+			StartTimeUnixNano: strconv.FormatInt(s.Start.UnixNano(), 10),
+			EndTimeUnixNano:   strconv.FormatInt(s.End.UnixNano(), 10),
+		}
+		for _, attr := range s.Attrs {
+			span.Attributes = append(span.Attributes, otlpAttr(attr.Key, attr.Value))
+		}
+		if s.Err != "" {
+			span.Status = otlpStatus{Code: otlpStatusError, Message: s.Err}
+		}
+		spans = append(spans, span)
+	}
+	return otlpRequest{ResourceSpans: []otlpResourceSpans{{
+		Resource:   otlpResource{Attributes: []otlpKeyValue{otlpAttr("service.name", e.serviceName)}},
+		ScopeSpans: []otlpScopeSpans{{Scope: otlpScope{Name: "agent"}, Spans: spans}},
+	}}}
+}
+
+// otlpAttr encodes an attribute as an OTLP AnyValue.
+func otlpAttr(key string, value any) otlpKeyValue {
+	var v map[string]any
+	switch value := value.(type) {
+	case string:
+		v = map[string]any{"stringValue": value}
+	case bool:
+		v = map[string]any{"boolValue": value}
+	case int:
+		v = map[string]any{"intValue": strconv.FormatInt(int64(value), 10)}
+	case int64:
+		v = map[string]any{"intValue": strconv.FormatInt(value, 10)}
+	case float64:
+		v = map[string]any{"doubleValue": value}
+	default:
+		v = map[string]any{"stringValue": fmt.Sprint(jsonValue(value))}
+	}
+	return otlpKeyValue{Key: key, Value: v}
+}
@@ -0,0 +1,31 @@
+// Package tracetest provides an exporter for checking in tests the spans the
+// agent records.
+package tracetest
+
+import (
+	"context"
+	"testing"
+
+	"agent/tracing"
+)
+
+// NewInMemoryExporter returns an exporter that keeps the spans it receives
+// in memory.
+func NewInMemoryExporter() *tracing.MemoryExporter {
+	return &tracing.MemoryExporter{}
+}
+
+// Install installs a new in-memory exporter as the only exporter for the
+// rest of the test, and returns it. Exporters are global, so tests calling
+// Install must not run in parallel.
+func Install(tb testing.TB) *tracing.MemoryExporter {
+	tb.Helper()
+	exporter := NewInMemoryExporter()
+	tracing.SetExporters(exporter)
+	tb.Cleanup(func() {
+		if err := tracing.Shutdown(context.Background()); err != nil {
+			tb.Errorf("tracing shutdown: %v", err)
+		}
+	})
+	return exporter
+}
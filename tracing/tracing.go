@@ -0,0 +1,192 @@
+// Package tracing records spans of the agent's work, such as model requests
+// and tool calls, following the OpenTelemetry data model. Spans are only
+// recorded while an exporter is installed.
+package tracing
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// SpanData is a finished span as handed to exporters.
+type SpanData struct {
+	TraceID  string // 32 hex digits
+	SpanID   string // 16 hex digits
+	ParentID string // empty for a root span
+	Name     string
+	Start    time.Time
+	End      time.Time
+	Attrs    []Attr
+	// Err is the error recorded on the span, if any.
+	Err string
+	// Lane groups spans for display as a timeline: a span shares its
+	// parent's lane unless it runs concurrently with a sibling.
+	Lane int
+}
+
+// Attr is a span attribute. Values are strings, bools, integers or floats;
+// other values are formatted with fmt.
+type Attr struct {
+	Key   string
+	Value any
+}
+
+// Exporter receives finished spans.
+type Exporter interface {
+	ExportSpan(SpanData)
+	// Shutdown flushes buffered spans and releases resources.
+	Shutdown(ctx context.Context) error
+}
+
+var (
+	mu        sync.Mutex
+	exporters []Exporter
+	enabled   atomic.Bool
+	lanes     atomic.Int64
+)
+
+// SetExporters installs the exporters spans are sent to, replacing any
+// installed before. With none, spans are not recorded.
+func SetExporters(e ...Exporter) {
+	mu.Lock()
+	defer mu.Unlock()
+	exporters = e
+	enabled.Store(len(e) > 0)
+}
+
+// Shutdown flushes and removes the installed exporters.
+func Shutdown(ctx context.Context) error {
+	mu.Lock()
+	old := exporters
+	exporters = nil
+	enabled.Store(false)
+	mu.Unlock()
+
+	var errs []error
+	for _, e := range old {
+		errs = append(errs, e.Shutdown(ctx))
+	}
+	return errors.Join(errs...)
+}
+
+// Span is a timed operation. A nil *Span, as returned while tracing is
+// disabled, ignores every method call.
+type Span struct {
+	mu     sync.Mutex
+	data   SpanData
+	parent *Span
+	active int // children started and not yet ended
+	ended  bool
+}
+
+type spanKey struct{}
+
+// Start begins a span named name as a child of the span in ctx, if any, and
+// returns a context carrying the new span. Attributes are given as
+// alternating keys and values.
+func Start(ctx context.Context, name string, attrs ...any) (context.Context, *Span) {
+	if !enabled.Load() {
+		return ctx, nil
+	}
+	span := &Span{data: SpanData{
+		SpanID: newID(8),
+		Name:   name,
+		Start:  time.Now(),
+		Attrs:  makeAttrs(attrs),
+	}}
+	if parent, ok := ctx.Value(spanKey{}).(*Span); ok && parent != nil {
+		span.parent = parent
+		parent.mu.Lock()
+		span.data.TraceID, span.data.ParentID = parent.data.TraceID, parent.data.SpanID
+		span.data.Lane = parent.data.Lane
+		if parent.active > 0 {
+			span.data.Lane = int(lanes.Add(1))
+		}
+		parent.active++
+		parent.mu.Unlock()
+	} else {
+		span.data.TraceID = newID(16)
+		span.data.Lane = int(lanes.Add(1))
+	}
+	return context.WithValue(ctx, spanKey{}, span), span
+}
+
+// SetAttributes sets attributes given as alternating keys and values,
+// replacing those with the same keys.
+func (s *Span) SetAttributes(attrs ...any) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+next:
+	for _, attr := range makeAttrs(attrs) {
+		for i := range s.data.Attrs {
+			if s.data.Attrs[i].Key == attr.Key {
+				s.data.Attrs[i].Value = attr.Value
+				continue next
+			}
+		}
+		s.data.Attrs = append(s.data.Attrs, attr)
+	}
+}
+
+// RecordError marks the span as failed with err, if err is not nil.
+func (s *Span) RecordError(err error) {
+	if s == nil || err == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.data.Err = err.Error()
+}
+
+// End finishes the span and exports it. Later calls have no effect.
+func (s *Span) End() {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	if s.ended {
+		s.mu.Unlock()
+		return
+	}
+	s.ended = true
+	s.data.End = time.Now()
+	data := s.data
+	s.mu.Unlock()
+
+	if s.parent != nil {
+		s.parent.mu.Lock()
+		s.parent.active--
+		s.parent.mu.Unlock()
+	}
+
+	mu.Lock()
+	current := exporters
+	mu.Unlock()
+	for _, e := range current {
+		e.ExportSpan(data)
+	}
+}
+
+// makeAttrs converts alternating keys and values to attributes.
+func makeAttrs(kv []any) []Attr {
+	attrs := make([]Attr, 0, len(kv)/2)
+	for i := 0; i+1 < len(kv); i += 2 {
+		attrs = append(attrs, Attr{Key: fmt.Sprint(kv[i]), Value: kv[i+1]})
+	}
+	return attrs
+}
+
+func newID(n int) string {
+	b := make([]byte, n)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
@@ -0,0 +1,115 @@
+package transcript
+
+import (
+	"html/template"
+	"io"
+	"strings"
+)
+
+// WriteHTML writes t as a self-contained HTML page. Tool calls are
+// collapsible sections, closed by default unless they failed.
+func WriteHTML(w io.Writer, t *Transcript) error {
+	return htmlTemplate.Execute(w, t)
+}
+
+// diffLine is a line of a tool result with its class for highlighting.
+type diffLine struct {
+	Class string
+	Text  string
+}
+
+// resultLines splits a tool result into lines, classifying added, removed
+// and hunk header lines if the result holds a diff.
+func resultLines(result string) []diffLine {
+	diff := isDiff(result)
+	var lines []diffLine
+	for _, line := range strings.Split(strings.TrimRight(result, "\n"), "\n") {
+		class := ""
+		if diff {
+			switch {
+			case strings.HasPrefix(line, "+++ "), strings.HasPrefix(line, "--- "):
+				class = "file"
+			case strings.HasPrefix(line, "@@"):
+				class = "hunk"
+			case strings.HasPrefix(line, "+"):
+				class = "add"
+			case strings.HasPrefix(line, "-"):
+				class = "del"
+			}
+		}
+		lines = append(lines, diffLine{Class: class, Text: line})
+	}
+	return lines
+}
+
+var htmlTemplate = template.Must(template.New("transcript").Funcs(template.FuncMap{
+	"time":        formatTime,
+	"json":        indentJSON,
+	"resultLines": resultLines,
+	"trim":        strings.TrimSpace,
+}).Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>Session {{.SessionID}}</title>
+<style>
+body { font-family: -apple-system, "Segoe UI", Helvetica, Arial, sans-serif; max-width: 960px; margin: 2em auto; padding: 0 1em; color: #1f2328; line-height: 1.5; }
+header dl { display: grid; grid-template-columns: max-content auto; gap: 0.2em 1em; color: #59636e; }
+header dd { margin: 0; }
+h2 { border-bottom: 1px solid #d1d9e0; padding-bottom: 0.3em; margin-top: 2em; }
+.user, .assistant { white-space: pre-wrap; padding: 0.75em 1em; border-radius: 6px; margin: 0.75em 0; }
+.user { background: #ddf4ff; }
+.assistant { background: #f6f8fa; }
+.label { font-weight: 600; font-size: 0.85em; color: #59636e; margin-bottom: 0.25em; white-space: normal; }
+details { border: 1px solid #d1d9e0; border-radius: 6px; margin: 0.75em 0; }
+details.failed { border-color: #ff8182; }
+summary { cursor: pointer; padding: 0.5em 1em; background: #f6f8fa; border-radius: 6px; }
+summary code { font-weight: 600; }
+summary .meta { color: #59636e; font-size: 0.85em; margin-left: 0.5em; }
+details > div { padding: 0 1em 0.5em; }
+pre { background: #f6f8fa; padding: 0.75em; border-radius: 6px; overflow-x: auto; font-size: 0.85em; }
+pre span { display: block; min-height: 1.2em; }
+.add { background: #dafbe1; }
+.del { background: #ffebe9; }
+.hunk { color: #0550ae; }
+.file { font-weight: 600; }
+.error { color: #cf222e; }
+.usage { color: #59636e; font-size: 0.85em; }
+</style>
+</head>
+<body>
+<header>
+<h1>Session {{.SessionID}}</h1>
+<dl>
+<dt>Started</dt><dd>{{time .Started}}</dd>
+<dt>Ended</dt><dd>{{time .Ended}}</dd>
+<dt>Turns</dt><dd>{{len .Turns}}</dd>
+<dt>Tokens</dt><dd>{{.Usage.InputTokens}} input, {{.Usage.OutputTokens}} output</dd>
+</dl>
+</header>
+{{range .Turns}}
+<section>
+<h2>Turn {{.Number}}</h2>
+{{if .User}}<div class="user"><div class="label">User · {{time .Time}}</div>{{.User}}</div>{{end}}
+{{range .Steps}}
+{{if .Tool}}{{with .Tool}}
+<details{{if .Error}} class="failed" open{{end}}>
+<summary><code>{{.Name}}</code><span class="meta">{{if not .Done}}did not finish{{else if .Error}}failed after {{.DurationMS}} ms{{else}}{{.DurationMS}} ms{{end}}</span></summary>
+<div>
+{{if .Input}}<div class="label">Input</div><pre>{{json .Input}}</pre>{{end}}
+{{if .Error}}<div class="label">Error</div><pre class="error">{{.Error}}</pre>
+{{else if .Done}}<div class="label">Result</div><pre>{{range resultLines .Result}}<span{{if .Class}} class="{{.Class}}"{{end}}>{{.Text}}</span>{{end}}</pre>{{end}}
+</div>
+</details>
+{{end}}{{else if .Error}}
+<p class="error">Error: {{.Error}}</p>
+{{else}}
+<div class="assistant"><div class="label">Assistant</div>{{trim .Text}}</div>
+{{end}}
+{{end}}
+<p class="usage">Tokens: {{.Usage.InputTokens}} input, {{.Usage.OutputTokens}} output</p>
+</section>
+{{end}}
+</body>
+</html>
+`))
@@ -0,0 +1,107 @@
+package transcript
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+)
+
+// WriteMarkdown writes t as a Markdown document. Tool inputs and results
+// are put in fenced code blocks, diffs with diff highlighting.
+func WriteMarkdown(w io.Writer, t *Transcript) error {
+	bw := bufio.NewWriter(w)
+	fmt.Fprintf(bw, "# Session %s\n\n", t.SessionID)
+	fmt.Fprintf(bw, "- Started: %s\n", formatTime(t.Started))
+	fmt.Fprintf(bw, "- Ended: %s\n", formatTime(t.Ended))
+	fmt.Fprintf(bw, "- Turns: %d\n", len(t.Turns))
+	fmt.Fprintf(bw, "- Tokens: %d input, %d output\n", t.Usage.InputTokens, t.Usage.OutputTokens)
+
+	for _, turn := range t.Turns {
+		fmt.Fprintf(bw, "\n## Turn %d\n\n", turn.Number)
+		if turn.User != "" {
+			fmt.Fprintf(bw, "**User** (%s)\n\n%s\n", formatTime(turn.Time), quote(turn.User))
+		}
+		for _, step := range turn.Steps {
+			bw.WriteString("\n")
+			switch {
+			case step.Tool != nil:
+				writeMarkdownTool(bw, step.Tool)
+			case step.Error != "":
+				fmt.Fprintf(bw, "**Error:** %s\n", step.Error)
+			default:
+				fmt.Fprintf(bw, "**Assistant**\n\n%s\n", strings.TrimSpace(step.Text))
+			}
+		}
+		fmt.Fprintf(bw, "\n_Tokens: %d input, %d output_\n", turn.Usage.InputTokens, turn.Usage.OutputTokens)
+	}
+	return bw.Flush()
+}
+
+func writeMarkdownTool(w io.Writer, call *ToolCall) {
+	status := fmt.Sprintf("%d ms", call.DurationMS)
+	switch {
+	case !call.Done:
+		status = "did not finish"
+	case call.Error != "":
+		status = "failed after " + status
+	}
+	fmt.Fprintf(w, "**Tool `%s`** (%s)\n\n", call.Name, status)
+	if len(call.Input) > 0 {
+		fmt.Fprintf(w, "Input:\n\n%s\n", fence(indentJSON(call.Input), "json"))
+	}
+	switch {
+	case call.Error != "":
+		fmt.Fprintf(w, "\nError:\n\n%s\n", fence(call.Error, ""))
+	case call.Done:
+		lang := ""
+		if isDiff(call.Result) {
+			lang = "diff"
+		}
+		fmt.Fprintf(w, "\nResult:\n\n%s\n", fence(call.Result, lang))
+	}
+}
+
+// fence wraps s in a code block whose fence is longer than any run of
+// backticks inside it.
+func fence(s, lang string) string {
+	longest, run := 0, 0
+	for _, c := range s {
+		if c == '`' {
+			run++
+			longest = max(longest, run)
+		} else {
+			run = 0
+		}
+	}
+	marker := strings.Repeat("`", max(3, longest+1))
+	return marker + lang + "\n" + strings.TrimRight(s, "\n") + "\n" + marker
+}
+
+// quote renders s as a Markdown block quote.
+func quote(s string) string {
+	lines := strings.Split(strings.TrimRight(s, "\n"), "\n")
+	for i, line := range lines {
+		lines[i] = strings.TrimRight("> "+line, " ")
+	}
+	return strings.Join(lines, "\n")
+}
+
+// indentJSON pretty-prints raw JSON, returning it unchanged if it is invalid.
+func indentJSON(raw json.RawMessage) string {
+	var out bytes.Buffer
+	if err := json.Indent(&out, raw, "", "  "); err != nil {
+		return string(raw)
+	}
+	return out.String()
+}
+
+func formatTime(t time.Time) string {
+	if t.IsZero() {
+		return "unknown"
+	}
+	return t.Local().Format(time.DateTime)
+}
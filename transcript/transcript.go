@@ -0,0 +1,229 @@
+// Package transcript rebuilds a conversation from a session log and renders
+// it as Markdown, HTML or JSON.
+package transcript
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"time"
+
+	"agent/logger"
+)
+
+// Formats lists the supported output formats.
+var Formats = []string{"md", "html", "json"}
+
+// Transcript is the conversation recorded in a session log.
+type Transcript struct {
+	SessionID string    `json:"session_id"`
+	Started   time.Time `json:"started"`
+	Ended     time.Time `json:"ended"`
+	Turns     []*Turn   `json:"turns"`
+	Usage     Usage     `json:"usage"`
+}
+
+// Turn is a user message and everything the agent did in response.
+type Turn struct {
+	Number int       `json:"number"`
+	Time   time.Time `json:"time"`
+	User   string    `json:"user"`
+	Steps  []*Step   `json:"steps"`
+	Usage  Usage     `json:"usage"`
+}
+
+// Step is one entry of a turn: assistant text, a tool call with its result,
+// or an error. Exactly one of Text, Tool and Error is set.
+type Step struct {
+	Time  time.Time `json:"time"`
+	Text  string    `json:"text,omitempty"`
+	Tool  *ToolCall `json:"tool,omitempty"`
+	Error string    `json:"error,omitempty"`
+}
+
+// ToolCall is a tool call with its outcome.
+type ToolCall struct {
+	ID         string          `json:"id,omitempty"`
+	Name       string          `json:"name"`
+	Input      json.RawMessage `json:"input,omitempty"`
+	Result     string          `json:"result,omitempty"`
+	Error      string          `json:"error,omitempty"`
+	DurationMS int64           `json:"duration_ms"`
+	// Done is false if the session ended before the tool returned.
+	Done bool `json:"done"`
+}
+
+// Usage counts the tokens used by model responses.
+type Usage struct {
+	InputTokens  int64 `json:"input_tokens"`
+	OutputTokens int64 `json:"output_tokens"`
+}
+
+func (u *Usage) add(other Usage) {
+	u.InputTokens += other.InputTokens
+	u.OutputTokens += other.OutputTokens
+}
+
+// record is the subset of a log line used to rebuild the conversation.
+type record struct {
+	Time      time.Time       `json:"time"`
+	Msg       string          `json:"msg"`
+	SessionID string          `json:"session_id"`
+	Event     string          `json:"event"`
+	Text      string          `json:"text"`
+	ToolUseID string          `json:"tool_use_id"`
+	Tool      string          `json:"tool"`
+	Input     json.RawMessage `json:"input"`
+	Result    string          `json:"result"`
+	Error     string          `json:"error"`
+	Duration  int64           `json:"duration_ms"`
+	Usage     Usage           `json:"usage"`
+}
+
+// Load reads the transcript of a session from its log files.
+func Load(session logger.Session) (*Transcript, error) {
+	t := &Transcript{SessionID: session.ID}
+	for _, path := range session.Files {
+		file, err := os.Open(path)
+		if err != nil {
+			return nil, err
+		}
+		err = t.read(file)
+		file.Close()
+		if err != nil {
+			return nil, fmt.Errorf("failed to read %s: %w", path, err)
+		}
+	}
+	return t, nil
+}
+
+// read adds the events of one log file to t. Lines that are not JSON, such
+// as those of older logs, are skipped.
+func (t *Transcript) read(r io.Reader) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), 64*1024*1024)
+	for scanner.Scan() {
+		var rec record
+		if err := json.Unmarshal(scanner.Bytes(), &rec); err != nil {
+			continue
+		}
+		t.add(rec)
+	}
+	return scanner.Err()
+}
+
+// add applies one log record to the transcript.
+func (t *Transcript) add(rec record) {
+	if t.Started.IsZero() {
+		t.Started = rec.Time
+	}
+	t.Ended = rec.Time
+
+	if rec.Event == logger.EventUserMessage {
+		t.Turns = append(t.Turns, &Turn{Number: len(t.Turns) + 1, Time: rec.Time, User: rec.Text})
+		return
+	}
+	turn := t.currentTurn(rec.Time)
+	switch rec.Event {
+	case logger.EventModelResponse:
+		if strings.TrimSpace(rec.Text) != "" {
+			turn.Steps = append(turn.Steps, &Step{Time: rec.Time, Text: rec.Text})
+		}
+		turn.Usage.add(rec.Usage)
+		t.Usage.add(rec.Usage)
+	case logger.EventToolCall:
+		turn.Steps = append(turn.Steps, &Step{Time: rec.Time, Tool: &ToolCall{ID: rec.ToolUseID, Name: rec.Tool, Input: rec.Input}})
+	case logger.EventToolResult:
+		call := turn.pendingCall(rec.ToolUseID, rec.Tool)
+		if call == nil {
+			call = &ToolCall{ID: rec.ToolUseID, Name: rec.Tool}
+			turn.Steps = append(turn.Steps, &Step{Time: rec.Time, Tool: call})
+		}
+		call.Result, call.Error, call.DurationMS, call.Done = rec.Result, rec.Error, rec.Duration, true
+	case logger.EventError:
+		msg := rec.Msg
+		if rec.Error != "" {
+			msg += ": " + rec.Error
+		}
+		turn.Steps = append(turn.Steps, &Step{Time: rec.Time, Error: msg})
+	}
+}
+
+// currentTurn returns the last turn, starting one if events precede the
+// first user message.
+func (t *Transcript) currentTurn(at time.Time) *Turn {
+	if len(t.Turns) == 0 {
+		t.Turns = append(t.Turns, &Turn{Number: 1, Time: at})
+	}
+	return t.Turns[len(t.Turns)-1]
+}
+
+// pendingCall returns the unfinished call with the given ID, or for calls
+// without an ID the first unfinished call of the named tool.
+func (turn *Turn) pendingCall(id, name string) *ToolCall {
+	for _, step := range turn.Steps {
+		call := step.Tool
+		if call == nil || call.Done {
+			continue
+		}
+		if (id != "" && call.ID == id) || (id == "" && call.ID == "" && call.Name == name) {
+			return call
+		}
+	}
+	return nil
+}
+
+// Write renders t to w in the given format: md, html or json.
+func Write(w io.Writer, t *Transcript, format string) error {
+	switch format {
+	case "md", "markdown":
+		return WriteMarkdown(w, t)
+	case "html":
+		return WriteHTML(w, t)
+	case "json":
+		return WriteJSON(w, t)
+	}
+	return fmt.Errorf("unknown export format %q; use one of %s", format, strings.Join(Formats, ", "))
+}
+
+// Export renders the transcript of session to a file at path, which
+// defaults to session_<id>.<format> in the current directory. It returns the
+// path written.
+func Export(session logger.Session, format, path string) (string, error) {
+	if format == "markdown" {
+		format = "md"
+	}
+	t, err := Load(session)
+	if err != nil {
+		return "", err
+	}
+	if path == "" {
+		path = fmt.Sprintf("session_%s.%s", session.ID, format)
+	}
+	var out bytes.Buffer
+	if err := Write(&out, t, format); err != nil {
+		return "", err
+	}
+	if err := os.WriteFile(path, out.Bytes(), 0o644); err != nil {
+		return "", fmt.Errorf("failed to write transcript: %w", err)
+	}
+	return path, nil
+}
+
+// WriteJSON writes t as indented JSON.
+func WriteJSON(w io.Writer, t *Transcript) error {
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	enc.SetEscapeHTML(false)
+	return enc.Encode(t)
+}
+
+// isDiff reports whether a tool result contains a unified diff, as returned
+// by the editing tools.
+func isDiff(result string) bool {
+	return strings.Contains(result, "--- a/") && strings.Contains(result, "\n+++ b/")
+}